@@ -0,0 +1,322 @@
+package infra
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	. "github.com/infrago/base"
+)
+
+const (
+	defaultAdminHost = "127.0.0.1"
+	defaultAdminPort = 9099
+
+	adminRedacted = "******"
+)
+
+var (
+	errAdminUnauthorized = errors.New("admin token required")
+
+	adminSecretKey = regexp.MustCompile(`(?i)(secret|password|passwd|pwd|token|credential|private|dsn|apikey|api_key|accesskey|access_key)`)
+
+	admin = &adminModule{
+		config: adminConfig{Host: defaultAdminHost, Port: defaultAdminPort, Pprof: true},
+	}
+)
+
+type (
+	adminConfig struct {
+		Enable bool
+		Host   string
+		Port   int
+		Pprof  bool
+		Token  string
+	}
+
+	adminModule struct {
+		mutex  sync.Mutex
+		config adminConfig
+		server *http.Server
+	}
+
+	adminEntry struct {
+		Name   string `json:"name"`
+		Kind   string `json:"kind"`
+		Target string `json:"target"`
+		Desc   string `json:"desc,omitempty"`
+	}
+)
+
+func (m *adminModule) Register(string, Any) {}
+
+// Config reads [infrago.admin].
+func (m *adminModule) Config(global Map) {
+	runtimeCfg, ok := global["infrago"].(Map)
+	if !ok {
+		return
+	}
+	cfg, ok := runtimeCfg["admin"].(Map)
+	if !ok {
+		return
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if vv, ok := cfg["enable"].(bool); ok {
+		m.config.Enable = vv
+	}
+	if vv, ok := cfg["host"].(string); ok && vv != "" {
+		m.config.Host = vv
+	}
+	if vv := settingInt(cfg["port"]); vv > 0 {
+		m.config.Port = vv
+	}
+	if vv, ok := cfg["pprof"].(bool); ok {
+		m.config.Pprof = vv
+	}
+	if vv, ok := cfg["token"].(string); ok {
+		m.config.Token = vv
+	}
+}
+
+func (m *adminModule) Setup() {}
+func (m *adminModule) Open()  {}
+
+func (m *adminModule) Start() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if !m.config.Enable || m.server != nil {
+		return
+	}
+	addr := net.JoinHostPort(m.config.Host, strconv.Itoa(m.config.Port))
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		// the admin port is a side channel, the node keeps running without it.
+		hook.Log(nil, slog.LevelError, "admin listen failed", "addr", addr, "error", err)
+		return
+	}
+	if m.config.Token == "" && !adminLoopback(m.config.Host) {
+		hook.Log(nil, slog.LevelWarn, "admin server has no token and listens beyond loopback", "addr", addr)
+	}
+	m.server = &http.Server{
+		Handler:           adminGuard(m.config.Token, m.handler(m.config.Pprof)),
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+	go func(server *http.Server) {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			hook.Log(nil, slog.LevelError, "admin server stopped", "error", err)
+		}
+	}(m.server)
+	hook.Log(nil, slog.LevelInfo, "admin server listening", "addr", addr)
+}
+
+func (m *adminModule) Stop() {
+	m.mutex.Lock()
+	server := m.server
+	m.server = nil
+	m.mutex.Unlock()
+
+	if server == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_ = server.Shutdown(ctx)
+}
+
+func (m *adminModule) Close() {}
+
+func (m *adminModule) handler(withPprof bool) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /identity", func(res http.ResponseWriter, req *http.Request) {
+		adminJSON(res, http.StatusOK, Map{
+			"identity": Identity(),
+			"profiles": infrago.EffectiveProfiles(),
+		})
+	})
+	mux.HandleFunc("GET /entries", func(res http.ResponseWriter, req *http.Request) {
+		adminJSON(res, http.StatusOK, core.entryList())
+	})
+	mux.HandleFunc("GET /settings", func(res http.ResponseWriter, req *http.Request) {
+		adminJSON(res, http.StatusOK, redactSetting(Setting()))
+	})
+	mux.HandleFunc("GET /nodes", func(res http.ResponseWriter, req *http.Request) {
+		adminJSON(res, http.StatusOK, ListNodes())
+	})
+	mux.HandleFunc("GET /services", func(res http.ResponseWriter, req *http.Request) {
+		adminJSON(res, http.StatusOK, ListServices())
+	})
+	mux.HandleFunc("GET /stats", func(res http.ResponseWriter, req *http.Request) {
+		adminJSON(res, http.StatusOK, Stats())
+	})
+	mux.HandleFunc("GET /health", func(res http.ResponseWriter, req *http.Request) {
+		report := health.Health(req.Context())
+		code := http.StatusOK
+		if !report.Ready {
+			code = http.StatusServiceUnavailable
+		}
+		adminJSON(res, code, report)
+	})
+	mux.HandleFunc("GET /spans", func(res http.ResponseWriter, req *http.Request) {
+		adminJSON(res, http.StatusOK, TraceSpans())
+	})
+	mux.Handle("GET /metrics", MetricsHandler())
+	mux.HandleFunc("POST /invoke/{name}", m.invoke)
+
+	if withPprof {
+		mux.HandleFunc("/debug/pprof/", pprof.Index)
+		mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+		mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+		mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+		mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	}
+	return mux
+}
+
+// adminGuard requires Authorization: Bearer <token> when token is set.
+// /health stays open for probes, /invoke checks its own authed token.
+func adminGuard(token string, next http.Handler) http.Handler {
+	if token == "" {
+		return next
+	}
+	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/health" || strings.HasPrefix(req.URL.Path, "/invoke/") {
+			next.ServeHTTP(res, req)
+			return
+		}
+		given := strings.TrimSpace(strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer "))
+		if subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+			adminJSON(res, http.StatusUnauthorized, Map{"error": errAdminUnauthorized.Error()})
+			return
+		}
+		next.ServeHTTP(res, req)
+	})
+}
+
+// adminLoopback reports whether host only accepts local connections.
+func adminLoopback(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// invoke runs one entry with a JSON body, the caller must carry an authed token.
+func (m *adminModule) invoke(res http.ResponseWriter, req *http.Request) {
+	token := strings.TrimSpace(req.Header.Get("Authorization"))
+	token = strings.TrimSpace(strings.TrimPrefix(token, "Bearer "))
+	if token == "" {
+		adminJSON(res, http.StatusUnauthorized, Map{"error": errAdminUnauthorized.Error()})
+		return
+	}
+
+	meta := NewMeta().WithContext(req.Context())
+	if err := meta.Verify(token); err != nil {
+		adminJSON(res, http.StatusUnauthorized, Map{"error": err.Error()})
+		return
+	}
+	if !meta.Authed() {
+		adminJSON(res, http.StatusForbidden, Map{"error": errAdminUnauthorized.Error()})
+		return
+	}
+
+	value := Map{}
+	if req.ContentLength != 0 {
+		if err := json.NewDecoder(req.Body).Decode(&value); err != nil {
+			adminJSON(res, http.StatusBadRequest, Map{"error": err.Error()})
+			return
+		}
+	}
+
+	data, result := core.Invoke(meta, req.PathValue("name"), value)
+	result = defaultResult(result)
+	adminJSON(res, http.StatusOK, Map{
+		"code":   result.Code(),
+		"status": result.Status(),
+		"text":   result.Error(),
+		"data":   data,
+	})
+}
+
+func (e *coreModule) entryList() []adminEntry {
+	e.mutex.RLock()
+	out := make([]adminEntry, 0, len(e.entries))
+	for name, entry := range e.entries {
+		out = append(out, adminEntry{
+			Name:   name,
+			Kind:   entry.kind,
+			Target: entry.target,
+			Desc:   entry.Desc,
+		})
+	}
+	e.mutex.RUnlock()
+
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// redactSetting masks values whose key looks like a secret, and values decrypted from enc: config.
+func redactSetting(in Map) Map {
+	return redactConfig("setting", in)
+}
+
+// redactConfig masks in, whose keys sit under the dotted config path prefix.
+func redactConfig(prefix string, in Map) Map {
+	if prefix != "" {
+		prefix += "."
+	}
+	out := make(Map, len(in))
+	for key, value := range in {
+		if adminSecretKey.MatchString(key) || configSecrets.has(prefix+key) {
+			out[key] = adminRedacted
+			continue
+		}
+		out[key] = redactSettingValue(prefix+key, value)
+	}
+	return out
+}
+
+func redactSettingValue(path string, value Any) Any {
+	switch v := value.(type) {
+	case Map:
+		return redactConfig(path, v)
+	case []Map:
+		out := make([]Map, len(v))
+		for i, item := range v {
+			out[i] = redactConfig(path+"."+strconv.Itoa(i), item)
+		}
+		return out
+	case []Any:
+		out := make([]Any, len(v))
+		for i, item := range v {
+			out[i] = redactSettingValue(path, item)
+		}
+		return out
+	default:
+		return value
+	}
+}
+
+func adminJSON(res http.ResponseWriter, code int, value Any) {
+	res.Header().Set("Content-Type", "application/json; charset=utf-8")
+	res.WriteHeader(code)
+	encoder := json.NewEncoder(res)
+	encoder.SetIndent("", "  ")
+	_ = encoder.Encode(value)
+}
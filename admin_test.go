@@ -0,0 +1,146 @@
+package infra
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	. "github.com/infrago/base"
+)
+
+func TestAdminSettingsAreRedacted(t *testing.T) {
+	original := infrago
+	infrago = &infragoRuntime{
+		project: INFRAGO,
+		setting: Map{
+			"db":           Map{"host": "localhost", "password": "p@ss"},
+			"token_secret": "abc",
+		},
+	}
+	defer func() {
+		infrago = original
+	}()
+
+	recorder := httptest.NewRecorder()
+	admin.handler(false).ServeHTTP(recorder, httptest.NewRequest("GET", "/settings", nil))
+
+	out := Map{}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &out); err != nil {
+		t.Fatalf("decode settings: %v", err)
+	}
+	db := out["db"].(Map)
+	if db["host"] != "localhost" || db["password"] != adminRedacted || out["token_secret"] != adminRedacted {
+		t.Fatalf("unexpected redacted settings: %#v", out)
+	}
+}
+
+func TestAdminInvokeRequiresAuthedToken(t *testing.T) {
+	originalCore := core
+	core = &coreModule{
+		entries: map[string]coreEntry{
+			"demo.echo": {
+				kind: coreKindMethod,
+				Action: func(ctx *Context) Map {
+					return Map{"name": ctx.Value["name"]}
+				},
+			},
+		},
+	}
+	originalHook := hook
+	hook = &infragoHook{}
+	hook.AttachToken(adminTestTokenHook{})
+	defer func() {
+		core = originalCore
+		hook = originalHook
+	}()
+
+	handler := admin.handler(false)
+	call := func(token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/invoke/demo.echo", strings.NewReader(`{"name":"infrago"}`))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	if recorder := call(""); recorder.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without token, got %d", recorder.Code)
+	}
+	if recorder := call("bad"); recorder.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 for invalid token, got %d", recorder.Code)
+	}
+	if recorder := call("guest"); recorder.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 for unauthed token, got %d", recorder.Code)
+	}
+
+	recorder := call("admin")
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+	if !strings.Contains(recorder.Body.String(), `"name": "infrago"`) {
+		t.Fatalf("unexpected invoke output: %s", recorder.Body.String())
+	}
+}
+
+type adminTestTokenHook struct{}
+
+func (adminTestTokenHook) Sign(req Token) (string, error) { return req.Token, nil }
+func (adminTestTokenHook) Verify(token string) (Token, error) {
+	switch token {
+	case "admin":
+		return Token{Token: token, Auth: true}, nil
+	case "guest":
+		return Token{Token: token}, nil
+	}
+	return Token{}, errInvalidToken
+}
+func (adminTestTokenHook) RevokeToken(string, int64) error   { return nil }
+func (adminTestTokenHook) RevokeTokenID(string, int64) error { return nil }
+
+func TestAdminGuardRequiresToken(t *testing.T) {
+	handler := adminGuard("s3cret", admin.handler(false))
+	call := func(path, token string) int {
+		req := httptest.NewRequest("GET", path, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		return recorder.Code
+	}
+
+	if code := call("/entries", ""); code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without token, got %d", code)
+	}
+	if code := call("/entries", "wrong"); code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 with wrong token, got %d", code)
+	}
+	if code := call("/entries", "s3cret"); code != http.StatusOK {
+		t.Fatalf("expected 200 with token, got %d", code)
+	}
+	if code := call("/health", ""); code == http.StatusUnauthorized {
+		t.Fatalf("expected health open for probes")
+	}
+}
+
+func TestAdminStartLogsListenFailure(t *testing.T) {
+	busy, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer busy.Close()
+	originalHook := hook
+	hook = &infragoHook{}
+	defer func() { hook = originalHook }()
+
+	m := &adminModule{config: adminConfig{Enable: true, Host: "127.0.0.1", Port: busy.Addr().(*net.TCPAddr).Port}}
+	m.Start()
+	if m.server != nil {
+		t.Fatalf("expected no server after listen failure")
+	}
+}
@@ -0,0 +1,265 @@
+package infra
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+// W3C baggage limits, members beyond them are dropped.
+const (
+	baggageMaxMembers = 64
+	baggageMaxBytes   = 8192
+)
+
+var baggageAllow = &baggageAllowList{}
+
+type baggageAllowList struct {
+	mutex sync.RWMutex
+	keys  map[string]struct{}
+}
+
+// BaggageAllow sets the keys that may leave the process through Metadata or
+// the baggage header, call it without keys to read the current list.
+// An empty list means every key is allowed, see ResetBaggageAllow.
+func BaggageAllow(keys ...string) []string {
+	if len(keys) > 0 {
+		baggageAllow.set(keys)
+	}
+	return baggageAllow.list()
+}
+
+// ResetBaggageAllow clears the allow-list, so every key may leave the process again.
+func ResetBaggageAllow() {
+	baggageAllow.set(nil)
+}
+
+func (a *baggageAllowList) set(keys []string) {
+	next := make(map[string]struct{}, len(keys))
+	for _, key := range keys {
+		key = strings.TrimSpace(key)
+		if validBaggageKey(key) {
+			next[key] = struct{}{}
+		}
+	}
+	a.mutex.Lock()
+	a.keys = next
+	a.mutex.Unlock()
+}
+
+func (a *baggageAllowList) list() []string {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+	out := make([]string, 0, len(a.keys))
+	for key := range a.keys {
+		out = append(out, key)
+	}
+	sort.Strings(out)
+	return out
+}
+
+func (a *baggageAllowList) allowed(key string) bool {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+	if len(a.keys) == 0 {
+		return true
+	}
+	_, ok := a.keys[key]
+	return ok
+}
+
+// Baggage gets or sets one baggage item, setting an empty value removes it.
+// A new item is dropped once meta holds baggageMaxMembers items, and any value
+// is dropped when the items would exceed baggageMaxBytes.
+func (m *Meta) Baggage(key string, value ...string) string {
+	key = strings.TrimSpace(key)
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if len(value) > 0 {
+		if !validBaggageKey(key) {
+			return ""
+		}
+		if value[0] == "" {
+			delete(m.baggage, key)
+			return ""
+		}
+		if m.baggage == nil {
+			m.baggage = make(map[string]string, 0)
+		}
+		old, ok := m.baggage[key]
+		if !ok && len(m.baggage) >= baggageMaxMembers {
+			return ""
+		}
+		size := baggageSize(m.baggage) + len(key) + 1 + len(value[0])
+		if ok {
+			size -= len(key) + 1 + len(old)
+		} else if len(m.baggage) > 0 {
+			size++
+		}
+		if size > baggageMaxBytes {
+			return ""
+		}
+		m.baggage[key] = value[0]
+		return value[0]
+	}
+	return m.baggage[key]
+}
+
+// BaggageItems returns a copy of all baggage items, including local-only keys.
+func (m *Meta) BaggageItems() map[string]string {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	out := make(map[string]string, len(m.baggage))
+	for k, v := range m.baggage {
+		out[k] = v
+	}
+	return out
+}
+
+// ParseBaggage merges a W3C baggage header into meta.
+// Member properties are ignored, malformed members are skipped.
+func (m *Meta) ParseBaggage(header string) bool {
+	header = strings.TrimSpace(header)
+	if header == "" || len(header) > baggageMaxBytes {
+		return false
+	}
+	parsed := false
+	for _, member := range strings.Split(header, ",") {
+		if idx := strings.IndexByte(member, ';'); idx >= 0 {
+			member = member[:idx]
+		}
+		key, value, ok := strings.Cut(member, "=")
+		if !ok {
+			continue
+		}
+		key = strings.TrimSpace(key)
+		value, ok = baggageUnescape(strings.TrimSpace(value))
+		if !ok || value == "" || !validBaggageKey(key) {
+			continue
+		}
+		if m.Baggage(key, value) != "" {
+			parsed = true
+		}
+	}
+	return parsed
+}
+
+// BaggageHeader builds a W3C baggage header from the allowed baggage items.
+func (m *Meta) BaggageHeader() string {
+	m.mutex.RLock()
+	items := outboundBaggage(m.baggage)
+	m.mutex.RUnlock()
+	keys := make([]string, 0, len(items))
+	for key := range items {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	out := strings.Builder{}
+	for _, key := range keys {
+		member := key + "=" + baggageEscape(items[key])
+		size := len(member)
+		if out.Len() > 0 {
+			size++
+		}
+		if out.Len()+size > baggageMaxBytes {
+			break
+		}
+		if out.Len() > 0 {
+			out.WriteByte(',')
+		}
+		out.WriteString(member)
+	}
+	return out.String()
+}
+
+// baggageSize is the length of items as key=value members joined by commas.
+func baggageSize(items map[string]string) int {
+	size := 0
+	for k, v := range items {
+		if size > 0 {
+			size++
+		}
+		size += len(k) + 1 + len(v)
+	}
+	return size
+}
+
+// outboundBaggage filters items by the allow-list.
+func outboundBaggage(items map[string]string) map[string]string {
+	if len(items) == 0 {
+		return nil
+	}
+	out := make(map[string]string, len(items))
+	for k, v := range items {
+		if baggageAllow.allowed(k) {
+			out[k] = v
+		}
+	}
+	if len(out) == 0 {
+		return nil
+	}
+	return out
+}
+
+// validBaggageKey checks the RFC 7230 token syntax required for keys.
+func validBaggageKey(key string) bool {
+	if key == "" {
+		return false
+	}
+	for i := 0; i < len(key); i++ {
+		c := key[i]
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case strings.IndexByte("!#$%&'*+-.^_`|~", c) >= 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func baggageEscape(v string) string {
+	const hex = "0123456789ABCDEF"
+	out := make([]byte, 0, len(v))
+	for i := 0; i < len(v); i++ {
+		c := v[i]
+		// baggage-octet excludes controls, space, DQUOTE, comma, semicolon and backslash.
+		if c > 0x20 && c < 0x7f && c != '"' && c != ',' && c != ';' && c != '\\' && c != '%' {
+			out = append(out, c)
+			continue
+		}
+		out = append(out, '%', hex[c>>4], hex[c&0x0f])
+	}
+	return string(out)
+}
+
+func baggageUnescape(v string) (string, bool) {
+	if !strings.Contains(v, "%") {
+		return v, true
+	}
+	out := make([]byte, 0, len(v))
+	for i := 0; i < len(v); i++ {
+		if v[i] != '%' {
+			out = append(out, v[i])
+			continue
+		}
+		if i+2 >= len(v) || !isHexString(v[i+1:i+3]) {
+			return "", false
+		}
+		out = append(out, unhexByte(v[i+1])<<4|unhexByte(v[i+2]))
+		i += 2
+	}
+	return string(out), true
+}
+
+func unhexByte(c byte) byte {
+	switch {
+	case c >= '0' && c <= '9':
+		return c - '0'
+	case c >= 'a' && c <= 'f':
+		return c - 'a' + 10
+	default:
+		return c - 'A' + 10
+	}
+}
@@ -0,0 +1,96 @@
+package infra
+
+import (
+	"strings"
+	"testing"
+
+	. "github.com/infrago/base"
+)
+
+func TestParseAndEmitBaggageHeader(t *testing.T) {
+	meta := NewMeta()
+	if !meta.ParseBaggage("tenant=acme, request_id=r%201;ttl=5,bad key=x,empty=") {
+		t.Fatalf("expected baggage to parse")
+	}
+	if meta.Baggage("tenant") != "acme" || meta.Baggage("request_id") != "r 1" {
+		t.Fatalf("unexpected baggage: %#v", meta.BaggageItems())
+	}
+	if _, ok := meta.BaggageItems()["empty"]; ok {
+		t.Fatalf("expected empty member to be skipped")
+	}
+	if header := meta.BaggageHeader(); header != "request_id=r%201,tenant=acme" {
+		t.Fatalf("unexpected header %q", header)
+	}
+}
+
+func TestBaggageAllowListFiltersMetadata(t *testing.T) {
+	defer baggageAllow.set(nil)
+
+	meta := NewMeta()
+	meta.Baggage("tenant", "acme")
+	meta.Baggage("cohort", "beta")
+	BaggageAllow("tenant")
+
+	data := meta.Metadata()
+	if len(data.Baggage) != 1 || data.Baggage["tenant"] != "acme" {
+		t.Fatalf("expected only allowed baggage, got %#v", data.Baggage)
+	}
+	if meta.BaggageHeader() != "tenant=acme" {
+		t.Fatalf("expected header to be filtered, got %q", meta.BaggageHeader())
+	}
+
+	remote := NewMeta()
+	remote.Metadata(data)
+	if remote.Baggage("tenant") != "acme" || remote.Baggage("cohort") != "" {
+		t.Fatalf("unexpected restored baggage: %#v", remote.BaggageItems())
+	}
+}
+
+func TestBaggageMemberLimit(t *testing.T) {
+	meta := NewMeta()
+	for i := 0; i < baggageMaxMembers+5; i++ {
+		meta.Baggage("k"+string(rune('a'+i%26))+string(rune('a'+i/26)), "v")
+	}
+	if len(meta.BaggageItems()) != baggageMaxMembers {
+		t.Fatalf("expected %d members, got %d", baggageMaxMembers, len(meta.BaggageItems()))
+	}
+}
+
+func TestBaggageByteLimitOnSetAndRestore(t *testing.T) {
+	meta := NewMeta()
+	big := strings.Repeat("x", baggageMaxBytes)
+	if meta.Baggage("huge", big) != "" || meta.Baggage("huge") != "" {
+		t.Fatalf("expected oversized value dropped")
+	}
+	half := strings.Repeat("y", baggageMaxBytes/2)
+	meta.Baggage("a", half)
+	if meta.Baggage("b", half) != "" {
+		t.Fatalf("expected value past the byte limit dropped")
+	}
+	if meta.Baggage("a", "small") != "small" || meta.Baggage("b", half) != half {
+		t.Fatalf("expected room after shrinking an item")
+	}
+
+	remote := NewMeta()
+	remote.Metadata(Metadata{Baggage: map[string]string{"a": half, "b": half, "c": half}})
+	if items := remote.BaggageItems(); len(items) != 1 || items["a"] != half {
+		t.Fatalf("expected restore to keep the limit, got %d items", len(items))
+	}
+}
+
+func TestConfigEmptyBaggageClearsAllowList(t *testing.T) {
+	defer ResetBaggageAllow()
+	BaggageAllow("tenant")
+
+	rt := &infragoRuntime{setting: Map{}}
+	rt.runtimeConfig(Map{"infrago": Map{"baggage": []Any{}}})
+	if keys := BaggageAllow(); len(keys) != 0 {
+		t.Fatalf("expected empty config list to clear allow-list, got %v", keys)
+	}
+
+	BaggageAllow("tenant")
+	ResetBaggageAllow()
+	if keys := BaggageAllow(); len(keys) != 0 {
+		t.Fatalf("expected reset, got %v", keys)
+	}
+}
@@ -91,6 +91,21 @@ func (module *codecModule) Register(name string, value Any) {
 	}
 }
 
+// ConfigSchema declares the codec section, so numeric text like length = "7" is accepted.
+func (module *codecModule) ConfigSchema() (string, Vars) {
+	number := Var{Valid: configIntValid, Value: configIntValue}
+	return "codec", Vars{
+		"text":     Var{},
+		"digit":    Var{},
+		"salt":     Var{},
+		"length":   number,
+		"start":    Var{},
+		"timebits": number,
+		"nodebits": number,
+		"stepbits": number,
+	}
+}
+
 // Config loads codec config.
 func (module *codecModule) Config(global Map) {
 	cfg, ok := global["codec"].(Map)
@@ -0,0 +1,279 @@
+package infra
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	. "github.com/infrago/base"
+)
+
+const (
+	defaultHTTPConfigTimeout = 10 * time.Second
+	defaultHTTPConfigWatch   = 30 * time.Second
+)
+
+var errConfigNotModified = errors.New("config not modified")
+
+type (
+	// httpConfigSource fetches config from url for the http driver:
+	// --driver=http --url=https://... [--token=...|--token_file=...] [--format=toml]
+	// [--cache=path] [--timeout=10s] [--watch=30s]
+	// The last good response is cached on disk and used when the service is down.
+	httpConfigSource struct {
+		mutex  sync.Mutex
+		url    string
+		token  string
+		format string
+		cache  string
+		client *http.Client
+
+		etag       string
+		body       []byte
+		bodyFormat string
+		fresh      bool
+	}
+
+	// httpConfigCache is the on-disk form of the last good response.
+	httpConfigCache struct {
+		URL    string `json:"url"`
+		ETag   string `json:"etag,omitempty"`
+		Format string `json:"format"`
+		Body   string `json:"body"`
+	}
+)
+
+func newHTTPConfigSource(params Map) (*httpConfigSource, error) {
+	url, _ := params["url"].(string)
+	if url == "" {
+		return nil, errors.New("http config driver needs --url")
+	}
+
+	source := &httpConfigSource{url: url, client: &http.Client{Timeout: defaultHTTPConfigTimeout}}
+	source.format, _ = params["format"].(string)
+	if d, ok := configDuration(params["timeout"]); ok && d > 0 {
+		source.client.Timeout = d
+	}
+	if token, ok := params["token"].(string); ok {
+		source.token = token
+	}
+	if file, ok := params["token_file"].(string); ok && file != "" && source.token == "" {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("http config token: %w", err)
+		}
+		source.token = strings.TrimSpace(string(data))
+	}
+	source.cache, _ = params["cache"].(string)
+	if source.cache == "" {
+		source.cache = defaultHTTPConfigCache(url)
+	}
+	return source, nil
+}
+
+// defaultHTTPConfigCache places the cache under the user cache dir, named by url.
+func defaultHTTPConfigCache(url string) string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	sum := fnv.New64a()
+	_, _ = sum.Write([]byte(url))
+	return filepath.Join(dir, INFRAGO, "config-"+strconv.FormatUint(sum.Sum64(), 16)+".json")
+}
+
+// same reports whether params describe this source, so its etag and body are kept.
+func (s *httpConfigSource) same(params Map) bool {
+	url, _ := params["url"].(string)
+	return s != nil && s.url == url
+}
+
+// load returns the decoded config body and its key sources.
+// A body already fetched by the poller is used once, otherwise the url is fetched,
+// falling back to the disk cache when that fails.
+func (s *httpConfigSource) load() (Map, map[string]string, error) {
+	s.mutex.Lock()
+	fresh := s.fresh
+	s.fresh = false
+	s.mutex.Unlock()
+
+	if !fresh {
+		if err := s.fetch(false); err != nil {
+			s.mutex.Lock()
+			loaded := len(s.body) > 0
+			s.mutex.Unlock()
+			if !loaded {
+				if cacheErr := s.readCache(); cacheErr != nil {
+					return nil, nil, fmt.Errorf("config %s: %w", s.url, err)
+				}
+			}
+			hook.Log(nil, slog.LevelWarn, "config fetch failed, using last good config", "url", s.url, "cache", s.cache, "error", err)
+		}
+	}
+
+	s.mutex.Lock()
+	body, format := s.body, s.bodyFormat
+	s.mutex.Unlock()
+
+	cfg, err := decodeConfig(body, format)
+	if err != nil {
+		return nil, nil, fmt.Errorf("config %s: %w", s.url, err)
+	}
+	if cfg == nil {
+		cfg = Map{}
+	}
+	sources := map[string]string{}
+	recordConfigSources(sources, s.url, configKeyLines(body, format), cfg)
+	return cfg, sources, nil
+}
+
+// fetch gets the url, with If-None-Match when conditional. A body that does not
+// decode is rejected, so the cache only ever holds a good config.
+func (s *httpConfigSource) fetch(conditional bool) error {
+	req, err := http.NewRequest(http.MethodGet, s.url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json, application/toml, application/yaml;q=0.9, */*;q=0.5")
+	if s.token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.token)
+	}
+	s.mutex.Lock()
+	etag := s.etag
+	s.mutex.Unlock()
+	if conditional && etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode == http.StatusNotModified {
+		return errConfigNotModified
+	}
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %s", res.Status)
+	}
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+
+	format := s.format
+	if format == "" {
+		format = httpConfigFormat(res.Header.Get("Content-Type"), body)
+	}
+	if _, err := decodeConfig(body, format); err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	s.etag, s.body, s.bodyFormat = res.Header.Get("ETag"), body, format
+	s.mutex.Unlock()
+	s.writeCache()
+	return nil
+}
+
+// poll reports whether the config changed since the last fetch, keeping the new body for load.
+func (s *httpConfigSource) poll() bool {
+	s.mutex.Lock()
+	before := string(s.body)
+	s.mutex.Unlock()
+
+	err := s.fetch(true)
+	if errors.Is(err, errConfigNotModified) {
+		return false
+	}
+	if err != nil {
+		hook.Log(nil, slog.LevelWarn, "config poll failed", "url", s.url, "error", err)
+		return false
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if string(s.body) == before {
+		return false
+	}
+	s.fresh = true
+	return true
+}
+
+func (s *httpConfigSource) watch(interval time.Duration, done <-chan struct{}, changed func()) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+		if s.poll() {
+			changed()
+		}
+	}
+}
+
+func (s *httpConfigSource) writeCache() {
+	s.mutex.Lock()
+	data, err := json.Marshal(httpConfigCache{URL: s.url, ETag: s.etag, Format: s.bodyFormat, Body: string(s.body)})
+	s.mutex.Unlock()
+	if err == nil {
+		err = os.MkdirAll(filepath.Dir(s.cache), 0o700)
+	}
+	if err == nil {
+		// write then rename, a crash never leaves a torn cache behind.
+		tmp := s.cache + ".tmp"
+		if err = os.WriteFile(tmp, data, 0o600); err == nil {
+			err = os.Rename(tmp, s.cache)
+		}
+	}
+	if err != nil {
+		hook.Log(nil, slog.LevelWarn, "config cache write failed", "cache", s.cache, "error", err)
+	}
+}
+
+func (s *httpConfigSource) readCache() error {
+	data, err := os.ReadFile(s.cache)
+	if err != nil {
+		return err
+	}
+	cached := httpConfigCache{}
+	if err := json.Unmarshal(data, &cached); err != nil {
+		return err
+	}
+	if cached.URL != s.url {
+		return fmt.Errorf("config cache %s belongs to %s", s.cache, cached.URL)
+	}
+
+	s.mutex.Lock()
+	s.etag, s.body, s.bodyFormat = cached.ETag, []byte(cached.Body), cached.Format
+	s.mutex.Unlock()
+	return nil
+}
+
+// httpConfigFormat picks a format by content type, then by content.
+func httpConfigFormat(contentType string, body []byte) string {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch {
+	case strings.HasSuffix(mediaType, "json"):
+		return "json"
+	case strings.HasSuffix(mediaType, "toml"):
+		return "toml"
+	case strings.HasSuffix(mediaType, "yaml"), strings.HasSuffix(mediaType, "yml"):
+		return "yaml"
+	}
+	return detectConfigFormat(body)
+}
@@ -0,0 +1,146 @@
+package infra
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/infrago/base"
+)
+
+type configTestServer struct {
+	mutex    sync.Mutex
+	body     string
+	etag     string
+	requests int
+	notMod   int
+}
+
+func (s *configTestServer) set(body, etag string) {
+	s.mutex.Lock()
+	s.body, s.etag = body, etag
+	s.mutex.Unlock()
+}
+
+func (s *configTestServer) ServeHTTP(res http.ResponseWriter, req *http.Request) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.requests++
+	if req.Header.Get("Authorization") != "Bearer t0k" {
+		res.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	if match := req.Header.Get("If-None-Match"); match != "" && match == s.etag {
+		s.notMod++
+		res.WriteHeader(http.StatusNotModified)
+		return
+	}
+	res.Header().Set("Content-Type", "application/toml; charset=utf-8")
+	res.Header().Set("ETag", s.etag)
+	_, _ = res.Write([]byte(s.body))
+}
+
+func TestHTTPConfigSourceLoadsPollsAndCaches(t *testing.T) {
+	server := &configTestServer{}
+	server.set("[http]\nport = 80\n", `"v1"`)
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	cache := filepath.Join(t.TempDir(), "config.json")
+	params := Map{"url": ts.URL, "token": "t0k", "cache": cache}
+	source, err := newHTTPConfigSource(params)
+	if err != nil {
+		t.Fatalf("new source: %v", err)
+	}
+
+	cfg, sources, err := source.load()
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if cfg["http"].(Map)["port"] != int64(80) || sources["http.port"] != ts.URL+":2" {
+		t.Fatalf("unexpected config %#v sources %v", cfg, sources)
+	}
+
+	if source.poll() {
+		t.Fatalf("expected no change on matching etag")
+	}
+	server.set("[http]\nport = 8080\n", `"v2"`)
+	if !source.poll() {
+		t.Fatalf("expected change on new etag")
+	}
+	before := server.requests
+	cfg, _, err = source.load()
+	if err != nil || cfg["http"].(Map)["port"] != int64(8080) {
+		t.Fatalf("expected polled config, got %#v %v", cfg, err)
+	}
+	if server.requests != before || server.notMod != 1 {
+		t.Fatalf("expected load to reuse the polled body, requests %d->%d, 304s %d", before, server.requests, server.notMod)
+	}
+
+	// a fresh process with the service down starts from the cache.
+	ts.Close()
+	offline, _ := newHTTPConfigSource(params)
+	cfg, _, err = offline.load()
+	if err != nil || cfg["http"].(Map)["port"] != int64(8080) {
+		t.Fatalf("expected cached config, got %#v %v", cfg, err)
+	}
+}
+
+func TestHTTPConfigSourceRejectsBadResponses(t *testing.T) {
+	server := &configTestServer{}
+	server.set("[http\nport = ", `"bad"`)
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	source, _ := newHTTPConfigSource(Map{"url": ts.URL, "token": "t0k", "cache": filepath.Join(t.TempDir(), "c.json")})
+	if _, _, err := source.load(); err == nil || !strings.Contains(err.Error(), ts.URL) {
+		t.Fatalf("expected decode error naming url, got %v", err)
+	}
+
+	source, _ = newHTTPConfigSource(Map{"url": ts.URL, "token": "wrong", "cache": filepath.Join(t.TempDir(), "c.json")})
+	if _, _, err := source.load(); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Fatalf("expected auth error, got %v", err)
+	}
+	if _, err := newHTTPConfigSource(Map{}); err == nil {
+		t.Fatalf("expected missing url error")
+	}
+}
+
+func TestDefaultConfigHookHTTPDriverWatches(t *testing.T) {
+	server := &configTestServer{}
+	server.set("[setting]\nname = \"a\"\n", `"v1"`)
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	params := Map{"url": ts.URL, "token": "t0k", "cache": filepath.Join(t.TempDir(), "c.json"), "watch": "10ms"}
+	h := &defaultConfigHook{}
+	cfg, err := h.loadRemote(params)
+	if err != nil || cfg["setting"].(Map)["name"] != "a" {
+		t.Fatalf("load: %#v %v", cfg, err)
+	}
+
+	done := make(chan struct{})
+	changed := make(chan struct{}, 1)
+	go h.WatchConfig(done, func() {
+		select {
+		case changed <- struct{}{}:
+		default:
+		}
+	})
+	defer close(done)
+
+	server.set("[setting]\nname = \"b\"\n", `"v2"`)
+	select {
+	case <-changed:
+	case <-time.After(2 * time.Second):
+		t.Fatalf("expected change from http poll")
+	}
+	cfg, err = h.loadRemote(params)
+	if err != nil || cfg["setting"].(Map)["name"] != "b" {
+		t.Fatalf("reload: %#v %v", cfg, err)
+	}
+}
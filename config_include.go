@@ -0,0 +1,176 @@
+package infra
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	. "github.com/infrago/base"
+)
+
+const (
+	configIncludeKey = "include"
+	configDirName    = "config.d"
+)
+
+// configLoader reads one config file with its includes, each file decoded by its own format.
+type configLoader struct {
+	stack   []string
+	files   []string
+	sources map[string]string
+}
+
+// loadConfigTree loads file and its includes, then merges files of confDir in lexical order.
+// Included files are merged first, so the including file overrides them;
+// conf.d files are merged last and override the main file.
+// The loader returned lists every file read, for watching, and the file:line of each key.
+func loadConfigTree(file, format, confDir string) (Map, *configLoader, error) {
+	loader := &configLoader{sources: map[string]string{}}
+	cfg, err := loader.load(file, format)
+	if err != nil {
+		return nil, nil, err
+	}
+	if confDir == "" {
+		return cfg, loader, nil
+	}
+
+	files, err := configDirFiles(confDir)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, item := range files {
+		next, err := loader.load(item, "")
+		if err != nil {
+			return nil, nil, err
+		}
+		cfg = mergeMap(cfg, next)
+	}
+	return cfg, loader, nil
+}
+
+func (l *configLoader) load(file, format string) (Map, error) {
+	path, err := filepath.Abs(file)
+	if err != nil {
+		return nil, fmt.Errorf("config %s: %w", file, err)
+	}
+	for i, item := range l.stack {
+		if item == path {
+			chain := append(append([]string{}, l.stack[i:]...), path)
+			return nil, fmt.Errorf("config include cycle: %s", strings.Join(chain, " -> "))
+		}
+	}
+	l.stack = append(l.stack, path)
+	defer func() { l.stack = l.stack[:len(l.stack)-1] }()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("config %s: %w", file, err)
+	}
+	l.files = append(l.files, path)
+	if format == "" {
+		format = configFileFormat(path, data)
+	}
+	cfg, err := decodeConfig(data, format)
+	if err != nil {
+		return nil, fmt.Errorf("config %s: %w", file, err)
+	}
+	if cfg == nil {
+		cfg = Map{}
+	}
+
+	includes, err := configIncludes(cfg[configIncludeKey])
+	if err != nil {
+		return nil, fmt.Errorf("config %s: %w", file, err)
+	}
+	delete(cfg, configIncludeKey)
+	// recorded after includes load, the including file overrides their sources.
+	defer recordConfigSources(l.sources, configDisplayPath(path), configKeyLines(data, format), cfg)
+	if len(includes) == 0 {
+		return cfg, nil
+	}
+
+	out := Map{}
+	base := filepath.Dir(path)
+	for _, include := range includes {
+		if !filepath.IsAbs(include) {
+			include = filepath.Join(base, include)
+		}
+		matches := []string{include}
+		if strings.ContainsAny(include, "*?[") {
+			if matches, err = filepath.Glob(include); err != nil {
+				return nil, fmt.Errorf("config %s: include %s: %w", file, include, err)
+			}
+			sort.Strings(matches)
+		}
+		for _, match := range matches {
+			next, err := l.load(match, "")
+			if err != nil {
+				return nil, err
+			}
+			out = mergeMap(out, next)
+		}
+	}
+	return mergeMap(out, cfg), nil
+}
+
+// configIncludes reads include as one path or a list of paths.
+func configIncludes(value Any) ([]string, error) {
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return []string{v}, nil
+	case []string:
+		return v, nil
+	case []Any:
+		out := make([]string, 0, len(v))
+		for _, item := range v {
+			path, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("include must be a list of paths, got %T", item)
+			}
+			out = append(out, path)
+		}
+		return out, nil
+	default:
+		return nil, fmt.Errorf("include must be a path or a list of paths, got %T", value)
+	}
+}
+
+// configDirFiles lists config files of dir in lexical order, a missing dir is empty.
+func configDirFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("config dir %s: %w", dir, err)
+	}
+	files := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		switch strings.ToLower(filepath.Ext(entry.Name())) {
+		case ".toml", ".tml", ".json", ".yaml", ".yml":
+			files = append(files, filepath.Join(dir, entry.Name()))
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// configFileFormat picks a format by extension, then by content.
+func configFileFormat(file string, data []byte) string {
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".json":
+		return "json"
+	case ".toml", ".tml":
+		return "toml"
+	case ".yaml", ".yml":
+		return "yaml"
+	}
+	return detectConfigFormat(data)
+}
@@ -0,0 +1,68 @@
+package infra
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	. "github.com/infrago/base"
+)
+
+func writeConfigFile(t *testing.T, file, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(file, []byte(content), 0o644); err != nil {
+		t.Fatalf("write %s: %v", file, err)
+	}
+}
+
+func TestLoadConfigTreeMergesIncludesAndConfD(t *testing.T) {
+	dir := t.TempDir()
+	writeConfigFile(t, filepath.Join(dir, "base.json"), `{"http": {"port": 80, "host": "0.0.0.0"}, "setting": {"name": "base"}}`)
+	writeConfigFile(t, filepath.Join(dir, "shared", "secret.yaml"), "setting:\n  secret: s1\n")
+	writeConfigFile(t, filepath.Join(dir, "config.toml"), `include = ["base.json", "shared/*.yaml"]
+
+[http]
+port = 8080
+`)
+	writeConfigFile(t, filepath.Join(dir, "config.d", "20-b.toml"), "[setting]\nname = \"b\"\n")
+	writeConfigFile(t, filepath.Join(dir, "config.d", "10-a.toml"), "[setting]\nname = \"a\"\nregion = \"eu\"\n")
+	writeConfigFile(t, filepath.Join(dir, "config.d", "README.md"), "ignored")
+
+	cfg, err := loadConfigFromFile(Map{"file": filepath.Join(dir, "config.toml")})
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if _, ok := cfg["include"]; ok {
+		t.Fatalf("include directive must not leak into config")
+	}
+	http := cfg["http"].(Map)
+	if http["port"] != int64(8080) || http["host"] != "0.0.0.0" {
+		t.Fatalf("expected main file over include, got %#v", http)
+	}
+	setting := cfg["setting"].(Map)
+	if setting["name"] != "b" || setting["region"] != "eu" || setting["secret"] != "s1" {
+		t.Fatalf("unexpected setting: %#v", setting)
+	}
+}
+
+func TestLoadConfigTreeReportsCycleAndBadFile(t *testing.T) {
+	dir := t.TempDir()
+	writeConfigFile(t, filepath.Join(dir, "a.toml"), `include = "b.toml"`)
+	writeConfigFile(t, filepath.Join(dir, "b.toml"), `include = ["a.toml"]`)
+
+	_, _, err := loadConfigTree(filepath.Join(dir, "a.toml"), "", "")
+	if err == nil || !strings.Contains(err.Error(), "cycle") || !strings.Contains(err.Error(), "b.toml") {
+		t.Fatalf("expected cycle error naming files, got %v", err)
+	}
+
+	writeConfigFile(t, filepath.Join(dir, "main.toml"), `include = ["broken.json"]`)
+	writeConfigFile(t, filepath.Join(dir, "broken.json"), `{"http": `)
+	_, _, err = loadConfigTree(filepath.Join(dir, "main.toml"), "", "")
+	if err == nil || !strings.Contains(err.Error(), "broken.json") {
+		t.Fatalf("expected error naming broken file, got %v", err)
+	}
+}
@@ -0,0 +1,284 @@
+package infra
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	. "github.com/infrago/base"
+)
+
+// configInterp expands ${...} references in string values of a loaded config.
+//
+//	${NAME}          env var NAME, an error when unset
+//	${NAME:-value}   env var NAME, value when unset or empty
+//	${a.b.c}         config value at a.b.c, names with a dot are config keys,
+//	                 list items by index as in ${sites.0.url}, keys holding
+//	                 a dot as in ${setting.token.idLength}
+//	${a.b:-value}    config value at a.b, value when missing
+//	$${              a literal ${
+//
+// A value that is exactly one config reference keeps the referenced type.
+// Referenced keys are expanded first, cycles are reported with their chain.
+// An env var holding an enc: value is decrypted, and a value built from a
+// secret becomes a secret itself, so it stays redacted.
+type configInterp struct {
+	root     Map
+	resolved map[string]bool
+	stack    []string
+	secrets  map[string]bool
+}
+
+// interpolateConfig expands references of cfg in place. secrets are the paths
+// decrypted so far, the result adds every path whose value came from one, sorted.
+func interpolateConfig(cfg Map, secrets []string) ([]string, error) {
+	interp := &configInterp{root: cfg, resolved: map[string]bool{}, secrets: map[string]bool{}}
+	for _, path := range secrets {
+		interp.secrets[path] = true
+	}
+	if err := interp.walkMap("", cfg); err != nil {
+		return nil, err
+	}
+	paths := make([]string, 0, len(interp.secrets))
+	for path := range interp.secrets {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// secret reports whether the value at path is, or holds, a secret.
+func (c *configInterp) secret(path string) bool {
+	for key := range c.secrets {
+		if key == path || strings.HasPrefix(key, path+".") {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *configInterp) walkMap(prefix string, cfg Map) error {
+	keys := make([]string, 0, len(cfg))
+	for key := range cfg {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		path := prefix + key
+		if next, ok := cfg[key].(Map); ok {
+			if err := c.walkMap(path+".", next); err != nil {
+				return err
+			}
+			continue
+		}
+		if _, err := c.lookupAt(cfg, key, path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// lookup returns the expanded value at path, nil when no key matches.
+func (c *configInterp) lookup(path string) (Any, error) {
+	parent, key, ok := configFind(c.root, strings.Split(path, "."))
+	if !ok {
+		return nil, nil
+	}
+	return c.lookupAt(parent, key, path)
+}
+
+// lookupAt expands key of parent, found at path, and stores it back so it is
+// expanded once.
+func (c *configInterp) lookupAt(parent Any, key, path string) (Any, error) {
+	value, ok := configChild(parent, key)
+	if !ok || c.resolved[path] {
+		return value, nil
+	}
+
+	for i, item := range c.stack {
+		if item == path {
+			chain := append(append([]string{}, c.stack[i:]...), path)
+			return nil, fmt.Errorf("config interpolation cycle: %s", strings.Join(chain, " -> "))
+		}
+	}
+	c.stack = append(c.stack, path)
+	defer func() { c.stack = c.stack[:len(c.stack)-1] }()
+
+	value, err := c.expandValue(path, value)
+	if err != nil {
+		return nil, err
+	}
+	setConfigChild(parent, key, value)
+	c.resolved[path] = true
+	return value, nil
+}
+
+// configFind finds the map or list holding the last key of the path split in
+// keys, list items are addressed by index, as in sites.0.url. Keys may hold a
+// dot themselves, so the longest literal key is tried first, as token.idLength
+// under setting.
+func configFind(current Any, keys []string) (Any, string, bool) {
+	for n := len(keys); n > 0; n-- {
+		key := strings.Join(keys[:n], ".")
+		next, ok := configChild(current, key)
+		if !ok {
+			continue
+		}
+		if n == len(keys) {
+			return current, key, true
+		}
+		if parent, last, ok := configFind(next, keys[n:]); ok {
+			return parent, last, true
+		}
+	}
+	return nil, "", false
+}
+
+// configChild reads key of a map, or the item at index key of a list.
+func configChild(container Any, key string) (Any, bool) {
+	switch v := container.(type) {
+	case Map:
+		value, ok := v[key]
+		return value, ok
+	case []Any:
+		if i, err := strconv.Atoi(key); err == nil && i >= 0 && i < len(v) {
+			return v[i], true
+		}
+	case []Map:
+		if i, err := strconv.Atoi(key); err == nil && i >= 0 && i < len(v) {
+			return v[i], true
+		}
+	}
+	return nil, false
+}
+
+func setConfigChild(container Any, key string, value Any) {
+	switch v := container.(type) {
+	case Map:
+		v[key] = value
+	case []Any:
+		if i, err := strconv.Atoi(key); err == nil {
+			v[i] = value
+		}
+	case []Map:
+		// items of []Map are expanded in place, the Map itself never changes.
+		if i, err := strconv.Atoi(key); err == nil {
+			if item, ok := value.(Map); ok {
+				v[i] = item
+			}
+		}
+	}
+}
+
+func (c *configInterp) expandValue(path string, value Any) (Any, error) {
+	switch v := value.(type) {
+	case string:
+		return c.expand(path, v)
+	case []Any:
+		for i := range v {
+			item := path + "." + strconv.Itoa(i)
+			if _, err := c.lookupAt(v, strconv.Itoa(i), item); err != nil {
+				return nil, err
+			}
+			// a list is redacted as a whole, as decryptConfig marks it.
+			if c.secrets[item] {
+				delete(c.secrets, item)
+				c.secrets[path] = true
+			}
+		}
+		return v, nil
+	case []Map:
+		for i := range v {
+			if _, err := c.lookupAt(v, strconv.Itoa(i), path+"."+strconv.Itoa(i)); err != nil {
+				return nil, err
+			}
+		}
+		return v, nil
+	case Map:
+		if err := c.walkMap(path+".", v); err != nil {
+			return nil, err
+		}
+		return v, nil
+	default:
+		return value, nil
+	}
+}
+
+// expand replaces every reference in text, a lone reference keeps its type.
+func (c *configInterp) expand(path, text string) (Any, error) {
+	if !strings.Contains(text, "${") {
+		return text, nil
+	}
+	if strings.HasPrefix(text, "${") && strings.IndexByte(text, '}') == len(text)-1 {
+		return c.resolve(path, text[2:len(text)-1])
+	}
+
+	out := strings.Builder{}
+	for i := 0; i < len(text); {
+		switch {
+		case strings.HasPrefix(text[i:], "$${"):
+			out.WriteString("${")
+			i += 3
+		case strings.HasPrefix(text[i:], "${"):
+			end := strings.IndexByte(text[i+2:], '}')
+			if end < 0 {
+				return nil, fmt.Errorf("config %s: unterminated ${ in %q", path, text)
+			}
+			value, err := c.resolve(path, text[i+2:i+2+end])
+			if err != nil {
+				return nil, err
+			}
+			out.WriteString(fmt.Sprint(value))
+			i += end + 3
+		default:
+			out.WriteByte(text[i])
+			i++
+		}
+	}
+	return out.String(), nil
+}
+
+func (c *configInterp) resolve(path, ref string) (Any, error) {
+	name, fallback, hasFallback := strings.Cut(ref, ":-")
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, fmt.Errorf("config %s: empty reference ${%s}", path, ref)
+	}
+
+	if strings.Contains(name, ".") {
+		value, err := c.lookup(name)
+		if err != nil {
+			return nil, err
+		}
+		if value != nil {
+			if c.secret(name) {
+				c.secrets[path] = true
+			}
+			return value, nil
+		}
+		if hasFallback {
+			return fallback, nil
+		}
+		return nil, fmt.Errorf("config %s: ${%s} references a missing key", path, name)
+	}
+
+	value, ok := os.LookupEnv(name)
+	if hasFallback && value == "" {
+		return fallback, nil
+	}
+	if !ok {
+		return nil, fmt.Errorf("config %s: ${%s} is not set", path, name)
+	}
+	if strings.HasPrefix(value, secretPrefix) {
+		plain, err := decryptSecret(value)
+		if err != nil {
+			return nil, fmt.Errorf("config %s: ${%s}: %w", path, name, err)
+		}
+		c.secrets[path] = true
+		return plain, nil
+	}
+	return value, nil
+}
@@ -0,0 +1,150 @@
+package infra
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	. "github.com/infrago/base"
+)
+
+func TestInterpolateConfigResolvesEnvAndKeys(t *testing.T) {
+	t.Setenv("INFRAGO_TEST_DB_USER", "app")
+	t.Setenv("INFRAGO_TEST_EMPTY", "")
+
+	cfg := Map{
+		"db": Map{
+			"dsn":  "postgres://${INFRAGO_TEST_DB_USER}:${INFRAGO_TEST_EMPTY:-pw}@${setting.db_host}/app",
+			"port": "${setting.db_port}",
+			"note": "literal $${HOME} stays",
+		},
+		"setting": Map{
+			"db_host": "${setting.region}.db",
+			"region":  "eu",
+			"db_port": int64(5432),
+			"tags":    []Any{"${setting.region}", "x"},
+			"missing": "${setting.nope:-none}",
+		},
+	}
+	if _, err := interpolateConfig(cfg, nil); err != nil {
+		t.Fatalf("interpolate: %v", err)
+	}
+
+	db := cfg["db"].(Map)
+	if db["dsn"] != "postgres://app:pw@eu.db/app" {
+		t.Fatalf("unexpected dsn: %v", db["dsn"])
+	}
+	if db["port"] != int64(5432) {
+		t.Fatalf("lone reference must keep its type, got %#v", db["port"])
+	}
+	if db["note"] != "literal ${HOME} stays" {
+		t.Fatalf("unexpected escape result: %v", db["note"])
+	}
+	setting := cfg["setting"].(Map)
+	if setting["tags"].([]Any)[0] != "eu" || setting["missing"] != "none" {
+		t.Fatalf("unexpected setting: %#v", setting)
+	}
+}
+
+func TestInterpolateConfigReportsErrors(t *testing.T) {
+	_, err := interpolateConfig(Map{"a": Map{"x": "${a.y}", "y": "${a.x}"}}, nil)
+	if err == nil || !strings.Contains(err.Error(), "cycle") || !strings.Contains(err.Error(), "a.x -> a.y -> a.x") {
+		t.Fatalf("expected cycle error, got %v", err)
+	}
+
+	_, err = interpolateConfig(Map{"a": "${INFRAGO_TEST_SURELY_UNSET}"}, nil)
+	if err == nil || !strings.Contains(err.Error(), "INFRAGO_TEST_SURELY_UNSET") {
+		t.Fatalf("expected unset env error, got %v", err)
+	}
+
+	_, err = interpolateConfig(Map{"a": "x ${b"}, nil)
+	if err == nil || !strings.Contains(err.Error(), "unterminated") {
+		t.Fatalf("expected unterminated error, got %v", err)
+	}
+
+	_, err = interpolateConfig(Map{"a": "${setting.token.nope}", "setting": Map{"token.idLength": int64(1)}}, nil)
+	if err == nil || !strings.Contains(err.Error(), "missing key") {
+		t.Fatalf("expected missing key error, got %v", err)
+	}
+}
+
+func TestInterpolateConfigDottedKeys(t *testing.T) {
+	t.Setenv("INFRAGO_TEST_ID_LENGTH", "12")
+
+	cfg := Map{
+		"setting": Map{
+			"token.idLength": "${INFRAGO_TEST_ID_LENGTH}",
+			"token.prefix":   "${setting.region}-",
+			"region":         "eu",
+		},
+		"length": "${setting.token.idLength}",
+		"prefix": "x${setting.token.prefix}",
+	}
+	if _, err := interpolateConfig(cfg, nil); err != nil {
+		t.Fatalf("interpolate: %v", err)
+	}
+
+	setting := cfg["setting"].(Map)
+	if setting["token.idLength"] != "12" || setting["token.prefix"] != "eu-" {
+		t.Fatalf("expected dotted keys expanded, got %v", setting)
+	}
+	if cfg["length"] != "12" || cfg["prefix"] != "xeu-" {
+		t.Fatalf("expected references to dotted keys, got %v %v", cfg["length"], cfg["prefix"])
+	}
+}
+
+func TestLoadConfigFromFileInterpolatesAcrossFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeConfigFile(t, filepath.Join(dir, "config.toml"), "[http]\nurl = \"http://${setting.host}\"\n")
+	writeConfigFile(t, filepath.Join(dir, "config.d", "10-host.toml"), "[setting]\nhost = \"example.com\"\n")
+
+	cfg, err := loadConfigFromFile(Map{"file": filepath.Join(dir, "config.toml")})
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	originalHook := hook
+	hook = &infragoHook{}
+	hook.AttachConfig(&reloadTestConfig{cfg: cfg})
+	defer func() { hook = originalHook }()
+
+	loaded, err := (&infragoRuntime{}).loadConfig()
+	if err != nil {
+		t.Fatalf("load config: %v", err)
+	}
+	if url := loaded.cfg["http"].(Map)["url"]; url != "http://example.com" {
+		t.Fatalf("unexpected url: %v", url)
+	}
+}
+
+func TestInterpolateConfigInsideArraysOfTables(t *testing.T) {
+	cfg, err := decodeConfig([]byte("[setting]\nhost = \"https://a.io\"\n\n[[sites]]\nurl = \"${setting.host}/x\"\n\n[[sites]]\nurl = \"${sites.0.url}/y\"\n"), "toml")
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	cfg["list"] = []Any{Map{"url": "${setting.host}/z"}}
+	if _, err := interpolateConfig(cfg, nil); err != nil {
+		t.Fatalf("interpolate: %v", err)
+	}
+
+	at := func(path string) Any {
+		var value Any = cfg
+		for _, key := range strings.Split(path, ".") {
+			value, _ = configChild(value, key)
+		}
+		return value
+	}
+	for path, want := range map[string]string{
+		"sites.0.url": "https://a.io/x",
+		"sites.1.url": "https://a.io/x/y",
+		"list.0.url":  "https://a.io/z",
+	} {
+		if got := at(path); got != want {
+			t.Fatalf("expected %s = %s, got %#v", path, want, got)
+		}
+	}
+
+	_, err = interpolateConfig(Map{"sites": []Map{{"url": "${INFRAGO_TEST_SURELY_UNSET}"}}}, nil)
+	if err == nil || !strings.Contains(err.Error(), "sites.0.url") {
+		t.Fatalf("expected error naming sites.0.url, got %v", err)
+	}
+}
@@ -0,0 +1,129 @@
+package infra
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	. "github.com/infrago/base"
+)
+
+// configEnvPrefix marks env overrides of nested config keys,
+// INFRAGO__HTTP__PORT=8080 sets http.port to 8080.
+const configEnvPrefix = "INFRAGO__"
+
+// configOverlay collects overrides from env and --set flags, flags win over env.
+// Sources name the env var or flag behind each dotted key.
+func configOverlay() (Map, map[string]string) {
+	out, sources := configEnvOverlay(os.Environ())
+	set, setSources := configSetOverlay(os.Args[1:])
+	for key, source := range setSources {
+		sources[key] = source
+	}
+	return mergeMap(out, set), sources
+}
+
+// configEnvOverlay maps INFRAGO__A__B=v to {a: {b: v}}.
+func configEnvOverlay(envs []string) (Map, map[string]string) {
+	out, sources := Map{}, map[string]string{}
+	for _, kv := range envs {
+		key, value, ok := strings.Cut(kv, "=")
+		if !ok || !strings.HasPrefix(key, configEnvPrefix) {
+			continue
+		}
+		path := strings.Split(strings.TrimPrefix(key, configEnvPrefix), "__")
+		if dotted := setConfigPath(out, path, coerceConfigValue(value)); dotted != "" {
+			sources[dotted] = "env " + key
+		}
+	}
+	return out, sources
+}
+
+// configSetOverlay maps --set a.b=v and --set=a.b=v flags to {a: {b: v}}.
+func configSetOverlay(args []string) (Map, map[string]string) {
+	out, sources := Map{}, map[string]string{}
+	for i := 0; i < len(args); i++ {
+		item := ""
+		switch {
+		case args[i] == "--set" && i+1 < len(args):
+			item = args[i+1]
+			i++
+		case strings.HasPrefix(args[i], "--set="):
+			item = strings.TrimPrefix(args[i], "--set=")
+		default:
+			continue
+		}
+		key, value, ok := strings.Cut(item, "=")
+		if !ok {
+			continue
+		}
+		if dotted := setConfigPath(out, strings.Split(key, "."), coerceConfigValue(value)); dotted != "" {
+			sources[dotted] = "flag --set " + key
+		}
+	}
+	return out, sources
+}
+
+// setConfigPath sets value at a lower-cased key path, replacing non-map parents,
+// and returns the dotted path it set.
+func setConfigPath(cfg Map, path []string, value Any) string {
+	keys := make([]string, 0, len(path))
+	for _, key := range path {
+		if key = strings.ToLower(strings.TrimSpace(key)); key != "" {
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) == 0 {
+		return ""
+	}
+
+	current := cfg
+	for _, key := range keys[:len(keys)-1] {
+		next, ok := current[key].(Map)
+		if !ok {
+			next = Map{}
+			current[key] = next
+		}
+		current = next
+	}
+	current[keys[len(keys)-1]] = value
+	return strings.Join(keys, ".")
+}
+
+// coerceConfigValue converts text to int64, float64, bool or time.Duration.
+// Only canonical forms convert, the text must print back unchanged: integers
+// without sign or leading zeros, floats in shortest form with a dot, lowercase
+// true/false. So "010", "+1", "1e3", "1.50", a version like "1.10" or "True"
+// stay strings. Durations like "5s" convert, read them with configDuration,
+// which accepts the string form a config file gives as well.
+func coerceConfigValue(raw string) Any {
+	value := strings.TrimSpace(raw)
+	if v, err := strconv.ParseInt(value, 10, 64); err == nil && strconv.FormatInt(v, 10) == value {
+		return v
+	}
+	if v, err := strconv.ParseFloat(value, 64); err == nil && strconv.FormatFloat(v, 'f', -1, 64) == value && strings.Contains(value, ".") {
+		return v
+	}
+	if value == "true" || value == "false" {
+		return value == "true"
+	}
+	// ParseDuration takes a bare "0" or "-0" too, a duration must carry its unit.
+	if v, err := time.ParseDuration(value); err == nil && strings.ContainsAny(value, "hmsuµn") {
+		return v
+	}
+	return raw
+}
+
+// configDuration reads a duration given as time.Duration or text like "5s".
+func configDuration(value Any) (time.Duration, bool) {
+	switch v := value.(type) {
+	case time.Duration:
+		return v, true
+	case string:
+		d, err := time.ParseDuration(v)
+		return d, err == nil
+	default:
+		return 0, false
+	}
+}
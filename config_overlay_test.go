@@ -0,0 +1,68 @@
+package infra
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/infrago/base"
+)
+
+func TestConfigEnvOverlay(t *testing.T) {
+	out, sources := configEnvOverlay([]string{
+		"INFRAGO__HTTP__PORT=8080",
+		"INFRAGO__SETTING__API_KEY=abc",
+		"INFRAGO__HTTP__DEBUG=true",
+		"INFRAGO__HTTP__TIMEOUT=5s",
+		"INFRAGO__HTTP__RATIO=0.5",
+		"INFRAGO_NODE=n1",
+		"PATH=/bin",
+	})
+
+	http, ok := out["http"].(Map)
+	if !ok {
+		t.Fatalf("expected http section, got %#v", out)
+	}
+	if http["port"] != int64(8080) || http["debug"] != true || http["timeout"] != 5*time.Second || http["ratio"] != 0.5 {
+		t.Fatalf("unexpected coercion: %#v", http)
+	}
+	if out["setting"].(Map)["api_key"] != "abc" {
+		t.Fatalf("expected setting.api_key, got %#v", out["setting"])
+	}
+	if _, ok := out["node"]; ok {
+		t.Fatalf("single underscore env must stay a driver param")
+	}
+	if sources["http.port"] != "env INFRAGO__HTTP__PORT" {
+		t.Fatalf("unexpected source: %v", sources["http.port"])
+	}
+}
+
+func TestConfigSetOverlayWinsOverEnvAndFile(t *testing.T) {
+	file := Map{"http": Map{"port": int64(80), "host": "0.0.0.0"}}
+	env, _ := configEnvOverlay([]string{"INFRAGO__HTTP__PORT=8080"})
+	set, sources := configSetOverlay([]string{"--set", "http.port=9090", "--set=setting.name=demo", "--other"})
+	if sources["setting.name"] != "flag --set setting.name" {
+		t.Fatalf("unexpected source: %v", sources["setting.name"])
+	}
+
+	cfg := mergeMap(mergeMap(file, env), set)
+	http := cfg["http"].(Map)
+	if http["port"] != int64(9090) || http["host"] != "0.0.0.0" {
+		t.Fatalf("unexpected merged http: %#v", http)
+	}
+	if cfg["setting"].(Map)["name"] != "demo" {
+		t.Fatalf("expected setting.name from --set=, got %#v", cfg["setting"])
+	}
+}
+
+func TestCoerceConfigValueKeepsText(t *testing.T) {
+	for _, raw := range []string{"nan", "inf", "yes", "0.0.0.0", "", "1.10", "1.50", "010", "+1", "1e3", "-0", "True"} {
+		if v := coerceConfigValue(raw); v != raw {
+			t.Fatalf("expected %q to stay text, got %#v", raw, v)
+		}
+	}
+	for raw, want := range map[string]Any{"8080": int64(8080), "-3": int64(-3), "1.5": 1.5, "true": true, " 7 ": int64(7), "5s": 5 * time.Second, "1m30s": 90 * time.Second} {
+		if v := coerceConfigValue(raw); v != want {
+			t.Fatalf("expected %q as %#v, got %#v", raw, want, v)
+		}
+	}
+}
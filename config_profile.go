@@ -0,0 +1,95 @@
+package infra
+
+import (
+	. "github.com/infrago/base"
+)
+
+const (
+	configProfilesKey = "profiles"
+	configRolesKey    = "roles"
+)
+
+// configProfileRole reads profile and role from cfg, [infrago] wins over the top level.
+func configProfileRole(cfg Map) (string, string) {
+	profile, role := "", ""
+	sections := []Map{cfg}
+	if runtimeCfg, ok := cfg["infrago"].(Map); ok {
+		sections = append(sections, runtimeCfg)
+	}
+	for _, section := range sections {
+		if vv, ok := section["profile"].(string); ok && normalizeToken(vv) != "" {
+			profile = normalizeToken(vv)
+		}
+		if vv, ok := section["role"].(string); ok && normalizeToken(vv) != "" {
+			role = normalizeToken(vv)
+		}
+	}
+	return profile, role
+}
+
+// applyConfigOverlays merges [profiles.<profile>] for each effective profile, then
+// [roles.<role>] for the effective role, over cfg. The selection sees overlay as well,
+// so INFRAGO__PROFILE or --set profile=prod pick the section, and overlay still wins
+// over section values once merged by the caller. Merged keys take the section's sources.
+func (c *infragoRuntime) applyConfigOverlays(cfg, overlay Map, sources map[string]string) Map {
+	if cfg == nil {
+		cfg = Map{}
+	}
+	profiles, _ := cfg[configProfilesKey].(Map)
+	roles, _ := cfg[configRolesKey].(Map)
+	delete(cfg, configProfilesKey)
+	delete(cfg, configRolesKey)
+	if len(profiles) == 0 && len(roles) == 0 {
+		return cfg
+	}
+
+	c.mutex.RLock()
+	configProfile, configRole := c.configProfile, c.configRole
+	runProfiles := append([]string{}, c.runProfiles...)
+	c.mutex.RUnlock()
+
+	if profile, _ := configProfileRole(mergeMap(cloneSettingMap(cfg), overlay)); profile != "" {
+		configProfile = profile
+	}
+	selected := selectProfiles(configProfile, runProfiles)
+	for _, profile := range selected {
+		if section, key, ok := configOverlaySection(profiles, profile); ok {
+			cfg = mergeMap(cfg, section)
+			moveConfigSources(sources, configProfilesKey+"."+key+".", section)
+		}
+	}
+
+	// a profile section may set the role, so pick it after merging profiles.
+	if _, role := configProfileRole(mergeMap(cloneSettingMap(cfg), overlay)); role != "" {
+		configRole = role
+	}
+	if section, key, ok := configOverlaySection(roles, selectRole(configRole, selected[0])); ok {
+		cfg = mergeMap(cfg, section)
+		moveConfigSources(sources, configRolesKey+"."+key+".", section)
+	}
+	return cfg
+}
+
+// configOverlaySection finds the section named name, keys compare normalized.
+func configOverlaySection(sections Map, name string) (Map, string, bool) {
+	for key, value := range sections {
+		if normalizeToken(key) != name {
+			continue
+		}
+		section, ok := value.(Map)
+		return section, key, ok
+	}
+	return nil, "", false
+}
+
+// moveConfigSources points the keys of a merged section at the lines they were written on.
+func moveConfigSources(sources map[string]string, prefix string, section Map) {
+	if sources == nil {
+		return
+	}
+	configLeaves("", section, func(path string, _ Any) {
+		if source, ok := sources[prefix+path]; ok {
+			sources[path] = source
+		}
+	})
+}
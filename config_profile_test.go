@@ -0,0 +1,63 @@
+package infra
+
+import (
+	"testing"
+
+	. "github.com/infrago/base"
+)
+
+func profileTestConfig() Map {
+	return Map{
+		"http": Map{"port": int64(80), "host": "0.0.0.0"},
+		"profiles": Map{
+			"dev":  Map{"http": Map{"port": int64(8080)}},
+			"Prod": Map{"http": Map{"port": int64(443)}, "infrago": Map{"role": "worker"}},
+		},
+		"roles": Map{
+			"worker": Map{"queue": Map{"threads": int64(16)}},
+		},
+	}
+}
+
+func TestApplyConfigOverlaysByRunProfileAndRole(t *testing.T) {
+	t.Setenv("INFRAGO_PROFILE", "")
+	t.Setenv("INFRAGO_ROLE", "")
+
+	c := &infragoRuntime{runProfiles: []string{"dev"}}
+	sources := map[string]string{"profiles.dev.http.port": "config.toml:7"}
+	cfg := c.applyConfigOverlays(profileTestConfig(), Map{}, sources)
+	if sources["http.port"] != "config.toml:7" {
+		t.Fatalf("expected merged key to keep its line, got %v", sources)
+	}
+	if _, ok := cfg["profiles"]; ok {
+		t.Fatalf("profiles section must not leak into config")
+	}
+	http := cfg["http"].(Map)
+	if http["port"] != int64(8080) || http["host"] != "0.0.0.0" {
+		t.Fatalf("expected dev overlay over base, got %#v", http)
+	}
+	if _, ok := cfg["queue"]; ok {
+		t.Fatalf("role overlay must not apply to role dev: %#v", cfg)
+	}
+
+	// the prod section sets the role, whose section then applies too.
+	cfg = c.applyConfigOverlays(profileTestConfig(), Map{"profile": "prod"}, nil)
+	if cfg["http"].(Map)["port"] != int64(443) {
+		t.Fatalf("expected prod overlay selected by overlay profile, got %#v", cfg["http"])
+	}
+	if cfg["queue"].(Map)["threads"] != int64(16) {
+		t.Fatalf("expected worker role overlay, got %#v", cfg)
+	}
+}
+
+func TestApplyConfigOverlaysEnvProfileWins(t *testing.T) {
+	t.Setenv("INFRAGO_PROFILE", "prod")
+	t.Setenv("INFRAGO_ROLE", "")
+
+	c := &infragoRuntime{runProfiles: []string{"dev"}}
+	cfg := mergeMap(profileTestConfig(), Map{"infrago": Map{"profile": "dev"}})
+	cfg = c.applyConfigOverlays(cfg, Map{}, nil)
+	if cfg["http"].(Map)["port"] != int64(443) {
+		t.Fatalf("expected env profile to win, got %#v", cfg["http"])
+	}
+}
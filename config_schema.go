@@ -0,0 +1,165 @@
+package infra
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strconv"
+	"strings"
+
+	. "github.com/infrago/base"
+)
+
+// ConfigSchemer is implemented by modules that declare the config section they read,
+// as a dotted path like "codec" or "infrago.admin", and a Vars schema for its keys.
+// Load maps the section with the schema before Config, so defaults and
+// type conversions are already applied when the module reads it.
+type ConfigSchemer interface {
+	ConfigSchema() (string, Vars)
+}
+
+// validateConfig checks every declared section of cfg and rewrites it with the mapped values.
+// All failures are returned together, each with where the key was set, unknown keys
+// are only logged. Keys filled by schema defaults are marked in sources, when given.
+func validateConfig(cfg Map, mods []Module, sources map[string]string) error {
+	problems := make([]string, 0)
+	problem := func(path, msg string) {
+		if source, ok := configSourceOf(sources, path); ok {
+			msg += " (" + source + ")"
+		}
+		problems = append(problems, path+": "+msg)
+	}
+	for _, mod := range mods {
+		schemer, ok := mod.(ConfigSchemer)
+		if !ok {
+			continue
+		}
+		section, schema := schemer.ConfigSchema()
+		if section == "" || len(schema) == 0 {
+			continue
+		}
+
+		data, exists, err := configSection(cfg, section)
+		if err != nil {
+			problem(section, err.Error())
+			continue
+		}
+		for _, key := range unknownConfigKeys(section, schema, data) {
+			source, _ := configSourceOf(sources, key)
+			hook.Log(nil, slog.LevelWarn, "unknown config key", "key", key, "source", source, "module", fmt.Sprintf("%T", mod))
+		}
+
+		names := make([]string, 0, len(schema))
+		for name := range schema {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		out := Map{}
+		failed := false
+		for _, name := range names {
+			res := basic.Mapping(Vars{name: schema[name]}, data, out, false, false)
+			if res != nil && res.Fail() {
+				problem(section+"."+name, res.Error())
+				failed = true
+			}
+		}
+		if failed {
+			continue
+		}
+		// absent optional keys map to nil, leave them out.
+		for key, value := range out {
+			if value == nil {
+				delete(out, key)
+			}
+		}
+		if len(out) == 0 {
+			continue
+		}
+		if !exists {
+			setConfigPath(cfg, strings.Split(section, "."), data)
+		}
+		for key, value := range out {
+			if _, ok := data[key]; !ok && sources != nil {
+				sources[section+"."+key] = sourceDefault
+			}
+			data[key] = value
+		}
+	}
+
+	if len(problems) > 0 {
+		return errors.New("invalid config:\n  " + strings.Join(problems, "\n  "))
+	}
+	return nil
+}
+
+// configIntValid accepts integers, integral floats and integer text, for schema Valid.
+func configIntValid(value Any, _ Var) bool {
+	_, ok := configInt(value)
+	return ok
+}
+
+// configIntValue converts what configIntValid accepts to int64, for schema Value.
+func configIntValue(value Any, _ Var) Any {
+	v, _ := configInt(value)
+	return v
+}
+
+func configInt(value Any) (int64, bool) {
+	switch v := value.(type) {
+	case int:
+		return int64(v), true
+	case int64:
+		return v, true
+	case float64:
+		return int64(v), v == float64(int64(v))
+	case string:
+		n, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+		return n, err == nil
+	default:
+		return 0, false
+	}
+}
+
+// configSection finds the table at a dotted path, a missing table is empty.
+func configSection(cfg Map, section string) (Map, bool, error) {
+	current := cfg
+	for _, key := range strings.Split(section, ".") {
+		value, ok := current[key]
+		if !ok || value == nil {
+			return Map{}, false, nil
+		}
+		next, ok := value.(Map)
+		if !ok {
+			return nil, false, fmt.Errorf("must be a table, got %T", value)
+		}
+		current = next
+	}
+	return current, true, nil
+}
+
+// unknownConfigKeys lists keys of data the schema does not declare, children included.
+func unknownConfigKeys(prefix string, schema Vars, data Map) []string {
+	out := make([]string, 0)
+	for key, value := range data {
+		field, ok := schema[key]
+		if !ok {
+			out = append(out, prefix+"."+key)
+			continue
+		}
+		if len(field.Children) == 0 {
+			continue
+		}
+		switch v := value.(type) {
+		case Map:
+			out = append(out, unknownConfigKeys(prefix+"."+key, field.Children, v)...)
+		case []Map:
+			for _, item := range v {
+				out = append(out, unknownConfigKeys(prefix+"."+key, field.Children, item)...)
+			}
+		}
+	}
+	sort.Strings(out)
+	return out
+}
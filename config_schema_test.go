@@ -0,0 +1,126 @@
+package infra
+
+import (
+	"log/slog"
+	"strings"
+	"testing"
+
+	. "github.com/infrago/base"
+)
+
+type schemaTestModule struct {
+	reloadTestModule
+	section string
+	schema  Vars
+}
+
+func (m *schemaTestModule) ConfigSchema() (string, Vars) {
+	return m.section, m.schema
+}
+
+type schemaTestLog struct {
+	keys []string
+}
+
+func (h *schemaTestLog) Log(_ *Meta, _ slog.Level, msg string, args ...Any) {
+	for i := 0; i+1 < len(args); i += 2 {
+		if args[i] == "key" {
+			h.keys = append(h.keys, args[i+1].(string))
+		}
+	}
+}
+
+func TestValidateConfigMapsSectionAndWarnsUnknownKeys(t *testing.T) {
+	originalHook := hook
+	logs := &schemaTestLog{}
+	hook = &infragoHook{}
+	hook.AttachLog(logs)
+	defer func() { hook = originalHook }()
+
+	cfg := Map{"codec": Map{"length": "7", "lenght": 9}}
+	if err := validateConfig(cfg, []Module{codec}, nil); err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+	section := cfg["codec"].(Map)
+	if section["length"] != int64(7) {
+		t.Fatalf("expected length converted to int64, got %#v", section["length"])
+	}
+	if _, ok := section["text"]; ok {
+		t.Fatalf("absent optional keys must stay absent: %#v", section)
+	}
+	if len(logs.keys) != 1 || logs.keys[0] != "codec.lenght" {
+		t.Fatalf("expected warning for codec.lenght, got %v", logs.keys)
+	}
+}
+
+func TestValidateConfigReportsAllErrors(t *testing.T) {
+	number := Var{Valid: configIntValid, Value: configIntValue}
+	mods := []Module{
+		&schemaTestModule{section: "infrago.pool", schema: Vars{
+			"size": number,
+			"name": Var{Required: true},
+			"mode": Var{Default: "fast"},
+		}},
+		&schemaTestModule{section: "cache", schema: Vars{"ttl": number}},
+	}
+
+	cfg := Map{"infrago": Map{"pool": Map{"size": "many"}}, "cache": Map{"ttl": 1.5}}
+	err := validateConfig(cfg, mods, nil)
+	if err == nil {
+		t.Fatalf("expected validation error")
+	}
+	for _, want := range []string{"infrago.pool.size", "infrago.pool.name", "cache.ttl"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Fatalf("expected %s in error, got %v", want, err)
+		}
+	}
+
+	cfg = Map{"infrago": Map{"pool": Map{"name": "p"}}}
+	sources := map[string]string{}
+	if err := validateConfig(cfg, mods, sources); err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+	if mode := cfg["infrago"].(Map)["pool"].(Map)["mode"]; mode != "fast" {
+		t.Fatalf("expected default applied, got %#v", mode)
+	}
+	if sources["infrago.pool.mode"] != "default" {
+		t.Fatalf("expected default source, got %v", sources)
+	}
+}
+
+func TestValidateConfigNamesSources(t *testing.T) {
+	number := Var{Valid: configIntValid, Value: configIntValue}
+	mods := []Module{
+		&schemaTestModule{section: "infrago.pool", schema: Vars{"size": number, "name": Var{Required: true}}},
+		&schemaTestModule{section: "cache", schema: Vars{"ttl": number}},
+		&schemaTestModule{section: "queue", schema: Vars{"depth": number}},
+	}
+	cfg := Map{
+		"infrago": Map{"pool": Map{"size": "many"}},
+		"cache":   Map{"ttl": "x"},
+		"queue":   "nope",
+	}
+	sources := map[string]string{
+		"infrago.pool.size": "config.toml:4",
+		"infrago.pool":      "config.toml:3",
+		"cache.ttl":         "env INFRAGO__CACHE__TTL",
+		"queue":             "flag --set queue",
+	}
+
+	err := validateConfig(cfg, mods, sources)
+	if err == nil {
+		t.Fatalf("expected validation error")
+	}
+	for _, want := range []string{
+		"infrago.pool.size: ",
+		"(config.toml:4)",
+		"infrago.pool.name: ",
+		"(config.toml:3)",
+		"(env INFRAGO__CACHE__TTL)",
+		"queue: must be a table, got string (flag --set queue)",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Fatalf("expected %q in error, got %v", want, err)
+		}
+	}
+}
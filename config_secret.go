@@ -0,0 +1,305 @@
+package infra
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	. "github.com/infrago/base"
+)
+
+const (
+	// secretPrefix marks an encrypted config value, enc:<codec>:<ciphertext>.
+	secretPrefix = "enc:"
+	// SECRET is the built-in AES-GCM codec for config secrets, keyed by
+	// INFRAGO_SECRET_KEY or the file named by INFRAGO_SECRET_KEY_FILE.
+	SECRET = "aes"
+)
+
+var (
+	errSecretKeyMissing = errors.New("secret key missing, set INFRAGO_SECRET_KEY or INFRAGO_SECRET_KEY_FILE")
+
+	configSecrets = &secretPaths{}
+	secretKeys    = &secretKeyring{err: errSecretKeyMissing}
+)
+
+// secretKeyring holds the cipher of the built-in codec, resolved once per load.
+type secretKeyring struct {
+	mutex sync.RWMutex
+	gcm   cipher.AEAD
+	err   error
+}
+
+// load resolves the key from the secret_key or secret_key_file driver param.
+// A missing or unreadable key only fails once a secret is used.
+func (k *secretKeyring) load(params Map) {
+	gcm, err := secretCipher(params)
+	k.mutex.Lock()
+	k.gcm, k.err = gcm, err
+	k.mutex.Unlock()
+}
+
+func (k *secretKeyring) aead() (cipher.AEAD, error) {
+	k.mutex.RLock()
+	defer k.mutex.RUnlock()
+	return k.gcm, k.err
+}
+
+// loadSecretKey reads the driver params once and resolves the secret key from them.
+func loadSecretKey() error {
+	_, params, err := parseConfigParams()
+	if err != nil {
+		return err
+	}
+	secretKeys.load(params)
+	return nil
+}
+
+// secretPaths remembers which config paths held encrypted values, for redaction.
+type secretPaths struct {
+	mutex sync.RWMutex
+	paths map[string]struct{}
+}
+
+func (s *secretPaths) set(paths []string) {
+	next := make(map[string]struct{}, len(paths))
+	for _, path := range paths {
+		next[path] = struct{}{}
+	}
+	s.mutex.Lock()
+	s.paths = next
+	s.mutex.Unlock()
+}
+
+func (s *secretPaths) has(path string) bool {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	_, ok := s.paths[path]
+	return ok
+}
+
+// decryptConfig replaces enc: values of cfg in place and returns their dotted paths, sorted.
+func decryptConfig(cfg Map) ([]string, error) {
+	paths := make([]string, 0)
+	if err := decryptConfigMap("", cfg, &paths); err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+func decryptConfigMap(prefix string, cfg Map, paths *[]string) error {
+	for key, value := range cfg {
+		next, found, err := decryptConfigValue(prefix+key, value, paths)
+		if err != nil {
+			return err
+		}
+		if found {
+			cfg[key] = next
+			*paths = append(*paths, prefix+key)
+		}
+	}
+	return nil
+}
+
+// decryptConfigValue reports found for a decrypted scalar or a list holding one,
+// a list is redacted as a whole.
+func decryptConfigValue(path string, value Any, paths *[]string) (Any, bool, error) {
+	switch v := value.(type) {
+	case string:
+		if !strings.HasPrefix(v, secretPrefix) {
+			return value, false, nil
+		}
+		plain, err := decryptSecret(v)
+		if err != nil {
+			return nil, false, fmt.Errorf("config %s: %w", path, err)
+		}
+		return plain, true, nil
+	case Map:
+		return value, false, decryptConfigMap(path+".", v, paths)
+	case []Map:
+		for i, item := range v {
+			if err := decryptConfigMap(fmt.Sprintf("%s.%d.", path, i), item, paths); err != nil {
+				return nil, false, err
+			}
+		}
+		return value, false, nil
+	case []Any:
+		found := false
+		for i, item := range v {
+			next, ok, err := decryptConfigValue(path, item, paths)
+			if err != nil {
+				return nil, false, err
+			}
+			if ok {
+				v[i] = next
+				found = true
+			}
+		}
+		return value, found, nil
+	default:
+		return value, false, nil
+	}
+}
+
+// decryptSecret decodes enc:<codec>:<ciphertext> through the codec module.
+func decryptSecret(text string) (Any, error) {
+	name, data, ok := strings.Cut(strings.TrimPrefix(text, secretPrefix), ":")
+	if !ok || name == "" {
+		return nil, errors.New("secret must look like enc:<codec>:<ciphertext>")
+	}
+	value, err := codec.Decrypt(name, data)
+	if err != nil {
+		return nil, fmt.Errorf("decrypt with %s: %w", name, err)
+	}
+	if bts, ok := value.([]byte); ok {
+		return string(bts), nil
+	}
+	return value, nil
+}
+
+// EncryptSecret encrypts value for pasting into config, as enc:<codec>:<ciphertext>.
+func EncryptSecret(codecName, value string) (string, error) {
+	if codecName == "" {
+		codecName = SECRET
+	}
+	data, err := codec.Encrypt(codecName, value)
+	if err != nil {
+		return "", err
+	}
+	return secretPrefix + codecName + ":" + data, nil
+}
+
+// secretCodec is the built-in AES-256-GCM codec, the key is hashed with SHA-256.
+func secretCodec() Codec {
+	return Codec{
+		Name: "AES-GCM加密",
+		Text: "配置密文，密钥来自 INFRAGO_SECRET_KEY 或 INFRAGO_SECRET_KEY_FILE",
+		Encode: func(v Any) (Any, error) {
+			gcm, err := secretKeys.aead()
+			if err != nil {
+				return nil, err
+			}
+			plain, ok := secretBytes(v)
+			if !ok {
+				return nil, errInvalidCodecData
+			}
+			nonce := make([]byte, gcm.NonceSize())
+			if _, err := rand.Read(nonce); err != nil {
+				return nil, err
+			}
+			return base64.RawURLEncoding.EncodeToString(gcm.Seal(nonce, nonce, plain, nil)), nil
+		},
+		Decode: func(d Any, _ Any) (Any, error) {
+			gcm, err := secretKeys.aead()
+			if err != nil {
+				return nil, err
+			}
+			text, ok := secretBytes(d)
+			if !ok {
+				return nil, errInvalidCodecData
+			}
+			data, err := base64.RawURLEncoding.DecodeString(string(text))
+			if err != nil || len(data) < gcm.NonceSize() {
+				return nil, errInvalidCodecData
+			}
+			plain, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+			if err != nil {
+				return nil, errInvalidCodecData
+			}
+			return string(plain), nil
+		},
+	}
+}
+
+func secretBytes(v Any) ([]byte, bool) {
+	switch vv := v.(type) {
+	case string:
+		return []byte(vv), true
+	case []byte:
+		return vv, true
+	default:
+		return nil, false
+	}
+}
+
+func secretCipher(params Map) (cipher.AEAD, error) {
+	key, err := secretKey(params)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256([]byte(key))
+	block, err := aes.NewCipher(sum[:])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// secretKey reads the key from the secret_key or secret_key_file driver param.
+func secretKey(params Map) (string, error) {
+	if key, ok := params["secret_key"].(string); ok && key != "" {
+		return key, nil
+	}
+	if file, ok := params["secret_key_file"].(string); ok && file != "" {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return "", fmt.Errorf("secret key: %w", err)
+		}
+		if key := strings.TrimSpace(string(data)); key != "" {
+			return key, nil
+		}
+	}
+	return "", errSecretKeyMissing
+}
+
+// secretCommand handles --encrypt [value] [--codec name], printing the enc: form.
+// Without a value the secret is read from stdin, keeping it out of shell history.
+func secretCommand(args []string, stdin io.Reader, stdout io.Writer) (bool, error) {
+	value, codecName, found, hasValue := "", SECRET, false, false
+	for i := 0; i < len(args); i++ {
+		switch {
+		case args[i] == "--encrypt":
+			found = true
+			if i+1 < len(args) && !strings.HasPrefix(args[i+1], "--") {
+				value, hasValue = args[i+1], true
+				i++
+			}
+		case strings.HasPrefix(args[i], "--encrypt="):
+			found, hasValue = true, true
+			value = strings.TrimPrefix(args[i], "--encrypt=")
+		case args[i] == "--codec" && i+1 < len(args):
+			codecName = args[i+1]
+			i++
+		case strings.HasPrefix(args[i], "--codec="):
+			codecName = strings.TrimPrefix(args[i], "--codec=")
+		}
+	}
+	if !found {
+		return false, nil
+	}
+	if !hasValue {
+		line, err := bufio.NewReader(stdin).ReadString('\n')
+		if err != nil && err != io.EOF {
+			return true, err
+		}
+		value = strings.TrimRight(line, "\r\n")
+	}
+
+	text, err := EncryptSecret(codecName, value)
+	if err != nil {
+		return true, err
+	}
+	_, err = fmt.Fprintln(stdout, text)
+	return true, err
+}
@@ -0,0 +1,187 @@
+package infra
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	. "github.com/infrago/base"
+)
+
+// useSecretKey loads the secret keyring from params for one test.
+func useSecretKey(t *testing.T, params Map) {
+	t.Helper()
+	secretKeys.load(params)
+	t.Cleanup(func() { secretKeys.load(Map{}) })
+}
+
+func TestDecryptConfigRoundTripAndRedaction(t *testing.T) {
+	useSecretKey(t, Map{"secret_key": "test-key"})
+	defer configSecrets.set(nil)
+
+	password, err := EncryptSecret("", "s3cret")
+	if err != nil {
+		t.Fatalf("encrypt: %v", err)
+	}
+	if !strings.HasPrefix(password, "enc:aes:") {
+		t.Fatalf("unexpected secret form: %s", password)
+	}
+
+	cfg := Map{
+		"setting": Map{"db": Map{"pass": password, "host": "db"}, "keys": []Any{"plain", password}},
+		"plain":   "enc-not-a-secret",
+	}
+	paths, err := decryptConfig(cfg)
+	if err != nil {
+		t.Fatalf("decrypt: %v", err)
+	}
+	if strings.Join(paths, ",") != "setting.db.pass,setting.keys" {
+		t.Fatalf("unexpected paths: %v", paths)
+	}
+	setting := cfg["setting"].(Map)
+	if setting["db"].(Map)["pass"] != "s3cret" || setting["keys"].([]Any)[1] != "s3cret" {
+		t.Fatalf("expected decrypted values, got %#v", setting)
+	}
+
+	configSecrets.set(paths)
+	out := redactSetting(setting)
+	if out["db"].(Map)["pass"] != adminRedacted || out["keys"] != adminRedacted || out["db"].(Map)["host"] != "db" {
+		t.Fatalf("unexpected redaction: %#v", out)
+	}
+}
+
+func TestDecryptConfigReportsPathAndKeyFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "key")
+	if err := os.WriteFile(file, []byte("file-key\n"), 0o600); err != nil {
+		t.Fatalf("write key: %v", err)
+	}
+	t.Setenv("INFRAGO_SECRET_KEY_FILE", file)
+	defer secretKeys.load(Map{})
+	if err := loadSecretKey(); err != nil {
+		t.Fatalf("load key: %v", err)
+	}
+
+	secret, err := EncryptSecret(SECRET, "v")
+	if err != nil {
+		t.Fatalf("encrypt with key file: %v", err)
+	}
+	// the key is read once per load, a changed file counts from the next load.
+	if err := os.WriteFile(file, []byte("other-key"), 0o600); err != nil {
+		t.Fatalf("write key: %v", err)
+	}
+	if _, err := decryptConfig(Map{"http": Map{"token": secret}}); err != nil {
+		t.Fatalf("expected key kept until the next load, got %v", err)
+	}
+	if err := loadSecretKey(); err != nil {
+		t.Fatalf("load key: %v", err)
+	}
+	_, err = decryptConfig(Map{"http": Map{"token": secret}})
+	if err == nil || !strings.Contains(err.Error(), "http.token") {
+		t.Fatalf("expected error naming http.token, got %v", err)
+	}
+
+	_, err = decryptConfig(Map{"a": "enc:nope:xx"})
+	if err == nil || !strings.Contains(err.Error(), "nope") {
+		t.Fatalf("expected unknown codec error, got %v", err)
+	}
+}
+
+func TestSecretCommandEncryptsFromArgsAndStdin(t *testing.T) {
+	useSecretKey(t, Map{"secret_key": "test-key"})
+
+	if ok, _ := secretCommand([]string{"--set", "a=b"}, nil, nil); ok {
+		t.Fatalf("expected no command without --encrypt")
+	}
+
+	out := &bytes.Buffer{}
+	if ok, err := secretCommand([]string{"--encrypt", "pw"}, nil, out); !ok || err != nil {
+		t.Fatalf("encrypt from args: %v %v", ok, err)
+	}
+	if plain, err := decryptSecret(strings.TrimSpace(out.String())); err != nil || plain != "pw" {
+		t.Fatalf("expected round trip, got %v %v", plain, err)
+	}
+
+	out.Reset()
+	if ok, err := secretCommand([]string{"--encrypt"}, strings.NewReader("from-stdin\n"), out); !ok || err != nil {
+		t.Fatalf("encrypt from stdin: %v %v", ok, err)
+	}
+	if plain, _ := decryptSecret(strings.TrimSpace(out.String())); plain != "from-stdin" {
+		t.Fatalf("expected stdin secret, got %v", plain)
+	}
+}
+
+func TestLoadConfigInterpolatesDecryptedSecrets(t *testing.T) {
+	useSecretKey(t, Map{"secret_key": "test-key"})
+	defer configSecrets.set(nil)
+
+	password, _ := EncryptSecret("", "s3cret")
+	token, _ := EncryptSecret("", "t0k")
+	t.Setenv("INFRAGO_TEST_API_TOKEN", token)
+
+	originalHook := hook
+	hook = &infragoHook{}
+	hook.AttachConfig(&reloadTestConfig{cfg: Map{"setting": Map{
+		"db_pass": password,
+		"dsn":     "postgres://u:${setting.db_pass}@h",
+		"copy":    "${setting.dsn}",
+		"auth":    "Bearer ${INFRAGO_TEST_API_TOKEN}",
+		"host":    "h",
+		"url":     "https://${setting.host}",
+	}}})
+	defer func() { hook = originalHook }()
+
+	loaded, err := (&infragoRuntime{}).loadConfig()
+	if err != nil {
+		t.Fatalf("load config: %v", err)
+	}
+	setting := loaded.cfg["setting"].(Map)
+	if setting["dsn"] != "postgres://u:s3cret@h" || setting["copy"] != setting["dsn"] || setting["auth"] != "Bearer t0k" {
+		t.Fatalf("expected decrypted references, got %#v", setting)
+	}
+	want := "setting.auth,setting.copy,setting.db_pass,setting.dsn"
+	if got := strings.Join(loaded.secrets, ","); got != want {
+		t.Fatalf("expected secrets %s, got %s", want, got)
+	}
+
+	configSecrets.set(loaded.secrets)
+	out := redactSetting(setting)
+	if out["dsn"] != adminRedacted || out["copy"] != adminRedacted || out["url"] != "https://h" {
+		t.Fatalf("unexpected redaction: %#v", out)
+	}
+}
+
+func TestRunCommandEncryptsAndPrintsConfig(t *testing.T) {
+	useSecretKey(t, Map{"secret_key": "test-key"})
+	defer configSecrets.set(nil)
+
+	c := &infragoRuntime{}
+	if done, err := c.runCommand([]string{"--set", "a=b"}, nil, nil); done || err != nil {
+		t.Fatalf("expected no mode to run, got %v %v", done, err)
+	}
+
+	out := &bytes.Buffer{}
+	if done, err := c.runCommand([]string{"--encrypt", "pw"}, nil, out); !done || err != nil {
+		t.Fatalf("encrypt: %v %v", done, err)
+	}
+	password := strings.TrimSpace(out.String())
+
+	originalHook := hook
+	hook = &infragoHook{}
+	hook.AttachConfig(&reloadTestConfig{cfg: Map{"setting": Map{"db_pass": password, "host": "h"}}})
+	defer func() { hook = originalHook }()
+
+	out.Reset()
+	if done, err := c.runCommand([]string{"--print-config=json"}, nil, out); !done || err != nil {
+		t.Fatalf("print config: %v %v", done, err)
+	}
+	if strings.Contains(out.String(), "pw") || strings.Contains(out.String(), password) || !strings.Contains(out.String(), adminRedacted) {
+		t.Fatalf("expected db_pass redacted, got %s", out.String())
+	}
+
+	secretKeys.load(Map{})
+	if done, err := c.runCommand([]string{"--encrypt", "pw"}, nil, out); !done || err == nil || !strings.Contains(err.Error(), "encrypt failed") {
+		t.Fatalf("expected encrypt to fail without a key, got %v %v", done, err)
+	}
+}
@@ -0,0 +1,447 @@
+package infra
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	. "github.com/infrago/base"
+)
+
+const (
+	sourceDefault = "default"
+	sourceConfig  = "config"
+)
+
+var (
+	configBareKey = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+	yamlKeyStart  = regexp.MustCompile(`^("[^"]*"|'[^']*'|[^:#'"\s][^:#]*?)\s*:(\s|$)`)
+)
+
+// configLeaves calls fn with the dotted path of every non-table value of cfg.
+func configLeaves(prefix string, cfg Map, fn func(string, Any)) {
+	for key, value := range cfg {
+		if next, ok := value.(Map); ok && len(next) > 0 {
+			configLeaves(prefix+key+".", next, fn)
+			continue
+		}
+		fn(prefix+key, value)
+	}
+}
+
+// recordConfigSources marks every leaf of cfg as coming from file, with the
+// line of the key when known, else the line of its nearest parent.
+func recordConfigSources(sources map[string]string, file string, lines map[string]int, cfg Map) {
+	configLeaves("", cfg, func(path string, _ Any) {
+		source := file
+		for key := path; key != ""; {
+			if line := lines[key]; line > 0 {
+				source = file + ":" + strconv.Itoa(line)
+				break
+			}
+			idx := strings.LastIndexByte(key, '.')
+			if idx < 0 {
+				break
+			}
+			key = key[:idx]
+		}
+		sources[path] = source
+	})
+}
+
+// resolveConfigSources keeps sources of the leaves of cfg, a leaf without one
+// takes its parent's, as values expanded from a reference do. Sources inside
+// an array of tables, as sites.1.url, are kept for its items.
+func resolveConfigSources(cfg Map, sources map[string]string) map[string]string {
+	out := make(map[string]string)
+	configLeaves("", cfg, func(path string, value Any) {
+		if _, ok := value.([]Map); ok {
+			for key, source := range sources {
+				if strings.HasPrefix(key, path+".") {
+					out[key] = source
+				}
+			}
+		}
+		if source, ok := configSourceOf(sources, path); ok {
+			out[path] = source
+			return
+		}
+		out[path] = sourceConfig
+	})
+	return out
+}
+
+// configSourceOf finds the source of path, else of its nearest parent.
+func configSourceOf(sources map[string]string, path string) (string, bool) {
+	for key := path; ; {
+		if source, ok := sources[key]; ok {
+			return source, true
+		}
+		idx := strings.LastIndexByte(key, '.')
+		if idx < 0 {
+			return "", false
+		}
+		key = key[:idx]
+	}
+}
+
+// configDisplayPath shows file relative to the working dir when it sits below it.
+func configDisplayPath(file string) string {
+	if wd, err := os.Getwd(); err == nil {
+		if rel, err := filepath.Rel(wd, file); err == nil && !strings.HasPrefix(rel, "..") {
+			return rel
+		}
+	}
+	return file
+}
+
+// configKeyLines finds the line of each dotted key in a config file, best effort.
+func configKeyLines(data []byte, format string) map[string]int {
+	switch strings.ToLower(format) {
+	case "json":
+		return jsonKeyLines(data)
+	case "yaml", "yml":
+		return yamlKeyLines(data)
+	default:
+		return tomlKeyLines(data)
+	}
+}
+
+func tomlKeyLines(data []byte) map[string]int {
+	lines := map[string]int{}
+	table := ""
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "" || strings.HasPrefix(line, "#"):
+		case strings.HasPrefix(line, "["):
+			name := strings.Trim(strings.SplitN(line, "]", 2)[0], "[ ")
+			if strings.HasPrefix(line, "[[") {
+				name = strings.Trim(strings.SplitN(line, "]]", 2)[0], "[ ")
+			}
+			table = tomlKeyPath(name)
+			if _, ok := lines[table]; !ok {
+				lines[table] = n
+			}
+		default:
+			key, _, ok := strings.Cut(line, "=")
+			if !ok || strings.ContainsAny(key, "[{") {
+				continue
+			}
+			path := tomlKeyPath(key)
+			if table != "" {
+				path = table + "." + path
+			}
+			if _, ok := lines[path]; !ok {
+				lines[path] = n
+			}
+		}
+	}
+	return lines
+}
+
+// tomlKeyPath turns a dotted TOML key with optional quotes into a plain dotted path.
+func tomlKeyPath(key string) string {
+	parts := strings.Split(strings.TrimSpace(key), ".")
+	for i, part := range parts {
+		parts[i] = strings.Trim(strings.TrimSpace(part), `"'`)
+	}
+	return strings.Join(parts, ".")
+}
+
+func yamlKeyLines(data []byte) map[string]int {
+	type level struct {
+		indent int
+		key    string
+	}
+	lines := map[string]int{}
+	stack := make([]level, 0)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; scanner.Scan(); n++ {
+		text := scanner.Text()
+		line := strings.TrimSpace(text)
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "-") || line == "---" {
+			continue
+		}
+		match := yamlKeyStart.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		indent := len(text) - len(strings.TrimLeft(text, " "))
+		for len(stack) > 0 && stack[len(stack)-1].indent >= indent {
+			stack = stack[:len(stack)-1]
+		}
+		stack = append(stack, level{indent, strings.Trim(match[1], `"'`)})
+
+		keys := make([]string, len(stack))
+		for i, item := range stack {
+			keys[i] = item.key
+		}
+		path := strings.Join(keys, ".")
+		if _, ok := lines[path]; !ok {
+			lines[path] = n
+		}
+	}
+	return lines
+}
+
+func jsonKeyLines(data []byte) map[string]int {
+	lines := map[string]int{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	lineAt := func(offset int64) int {
+		return bytes.Count(data[:offset], []byte("\n")) + 1
+	}
+
+	var walk func(prefix string) error
+	walk = func(prefix string) error {
+		token, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+		switch token {
+		case json.Delim('{'):
+			for decoder.More() {
+				key, err := decoder.Token()
+				if err != nil {
+					return err
+				}
+				path := prefix + fmt.Sprint(key)
+				lines[path] = lineAt(decoder.InputOffset())
+				if err := walk(path + "."); err != nil {
+					return err
+				}
+			}
+			_, err = decoder.Token()
+			return err
+		case json.Delim('['):
+			// list items are reported at the list key.
+			for decoder.More() {
+				if err := walk(prefix + "-."); err != nil {
+					return err
+				}
+			}
+			_, err = decoder.Token()
+			return err
+		}
+		return nil
+	}
+	_ = walk("")
+	return lines
+}
+
+// printConfigFormat reads --print-config[=toml|json|yaml], toml by default.
+func printConfigFormat(args []string) (string, bool) {
+	for i, arg := range args {
+		if strings.HasPrefix(arg, "--print-config=") {
+			return strings.ToLower(strings.TrimPrefix(arg, "--print-config=")), true
+		}
+		if arg != "--print-config" {
+			continue
+		}
+		if i+1 < len(args) {
+			switch next := strings.ToLower(args[i+1]); next {
+			case "toml", "json", "yaml", "yml":
+				return next, true
+			}
+		}
+		return "toml", true
+	}
+	return "", false
+}
+
+// printConfig writes cfg with secrets redacted, each key annotated with its source.
+// TOML and YAML carry the source as a trailing comment, JSON as a sibling sources object.
+func printConfig(w io.Writer, cfg Map, sources map[string]string, format string) error {
+	cfg = redactConfig("", cfg)
+	switch format {
+	case "json":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(Map{"config": cfg, "sources": sources})
+	case "yaml", "yml":
+		out := &bytes.Buffer{}
+		printYAMLMap(out, "", 0, cfg, sources)
+		_, err := w.Write(out.Bytes())
+		return err
+	case "toml", "":
+		out := &bytes.Buffer{}
+		printTOMLTable(out, "", "", cfg, sources)
+		_, err := w.Write(out.Bytes())
+		return err
+	default:
+		return fmt.Errorf("unknown config format %q, use toml, json or yaml", format)
+	}
+}
+
+// printTOMLTable writes cfg under the table header table, prefix is the dotted
+// path of cfg, with the index of an array-of-tables item, to look up sources.
+func printTOMLTable(out *bytes.Buffer, table, prefix string, cfg Map, sources map[string]string) {
+	keys := sortedConfigKeys(cfg)
+	tables := make([]string, 0)
+	for _, key := range keys {
+		switch v := cfg[key].(type) {
+		case Map:
+			if len(v) > 0 {
+				tables = append(tables, key)
+				continue
+			}
+		case []Map:
+			tables = append(tables, key)
+			continue
+		}
+		fmt.Fprintf(out, "%s = %s%s\n", printConfigKey(key), printConfigValue(cfg[key]), sourceComment(sources, prefix+key))
+	}
+	for _, key := range tables {
+		path := prefix + key
+		header := printConfigKey(key)
+		if table != "" {
+			header = table + "." + header
+		}
+		switch v := cfg[key].(type) {
+		case Map:
+			if out.Len() > 0 {
+				out.WriteByte('\n')
+			}
+			fmt.Fprintf(out, "[%s]\n", header)
+			printTOMLTable(out, header, path+".", v, sources)
+		case []Map:
+			for i, item := range v {
+				itemPath := path + "." + strconv.Itoa(i)
+				if out.Len() > 0 {
+					out.WriteByte('\n')
+				}
+				fmt.Fprintf(out, "[[%s]]%s\n", header, sourceComment(sources, itemPath))
+				printTOMLTable(out, header, itemPath+".", item, sources)
+			}
+		}
+	}
+}
+
+// printConfigKey keeps bare keys, others become a basic string, which TOML
+// and YAML both read.
+func printConfigKey(key string) string {
+	if configBareKey.MatchString(key) {
+		return key
+	}
+	return configQuote(key)
+}
+
+// configQuote quotes text with the escapes of a TOML basic string, unlike
+// strconv.Quote it never writes \x or \a, which TOML rejects.
+func configQuote(text string) string {
+	out := strings.Builder{}
+	out.WriteByte('"')
+	for _, r := range text {
+		switch r {
+		case '"':
+			out.WriteString(`\"`)
+		case '\\':
+			out.WriteString(`\\`)
+		case '\b':
+			out.WriteString(`\b`)
+		case '\t':
+			out.WriteString(`\t`)
+		case '\n':
+			out.WriteString(`\n`)
+		case '\f':
+			out.WriteString(`\f`)
+		case '\r':
+			out.WriteString(`\r`)
+		default:
+			if r < 0x20 || r == 0x7f {
+				fmt.Fprintf(&out, `\u%04X`, r)
+				continue
+			}
+			out.WriteRune(r)
+		}
+	}
+	out.WriteByte('"')
+	return out.String()
+}
+
+func printYAMLMap(out *bytes.Buffer, prefix string, depth int, cfg Map, sources map[string]string) {
+	indent := strings.Repeat("  ", depth)
+	for _, key := range sortedConfigKeys(cfg) {
+		path := prefix + key
+		if next, ok := cfg[key].(Map); ok && len(next) > 0 {
+			fmt.Fprintf(out, "%s%s:\n", indent, printConfigKey(key))
+			printYAMLMap(out, path+".", depth+1, next, sources)
+			continue
+		}
+		fmt.Fprintf(out, "%s%s: %s%s\n", indent, printConfigKey(key), printYAMLValue(cfg[key]), sourceComment(sources, path))
+	}
+}
+
+// printYAMLValue renders a value as JSON, which YAML reads as flow style.
+func printYAMLValue(value Any) string {
+	switch value.(type) {
+	case time.Duration, time.Time:
+		return printConfigValue(value)
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return strconv.Quote(fmt.Sprint(value))
+	}
+	return string(data)
+}
+
+// printConfigValue renders a value in TOML inline form.
+func printConfigValue(value Any) string {
+	switch v := value.(type) {
+	case time.Duration:
+		return configQuote(v.String())
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	case Map:
+		parts := make([]string, 0, len(v))
+		for _, key := range sortedConfigKeys(v) {
+			parts = append(parts, printConfigKey(key)+" = "+printConfigValue(v[key]))
+		}
+		return "{" + strings.Join(parts, ", ") + "}"
+	case []Any:
+		parts := make([]string, len(v))
+		for i, item := range v {
+			parts[i] = printConfigValue(item)
+		}
+		return "[" + strings.Join(parts, ", ") + "]"
+	case []string:
+		parts := make([]string, len(v))
+		for i, item := range v {
+			parts[i] = configQuote(item)
+		}
+		return "[" + strings.Join(parts, ", ") + "]"
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return strconv.Quote(fmt.Sprint(value))
+	}
+	return string(data)
+}
+
+// sourceComment annotates path with its source, else its nearest parent's,
+// as items of an array of tables take the source of the array.
+func sourceComment(sources map[string]string, path string) string {
+	if source, ok := configSourceOf(sources, path); ok {
+		return "  # " + source
+	}
+	return ""
+}
+
+func sortedConfigKeys(cfg Map) []string {
+	keys := make([]string, 0, len(cfg))
+	for key := range cfg {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
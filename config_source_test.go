@@ -0,0 +1,151 @@
+package infra
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	. "github.com/infrago/base"
+)
+
+func TestConfigKeyLinesPerFormat(t *testing.T) {
+	toml := configKeyLines([]byte("name = \"a\"\n\n[http]\nport = 80\n\"my.key\" = 1\n[[jobs]]\nid = 1\n"), "toml")
+	if toml["name"] != 1 || toml["http"] != 3 || toml["http.port"] != 4 || toml["http.my.key"] != 5 || toml["jobs.id"] != 7 {
+		t.Fatalf("unexpected toml lines: %v", toml)
+	}
+
+	yaml := configKeyLines([]byte("http:\n  port: 80\n  tls:\n    cert: \"a:b\"\nname: x\n"), "yaml")
+	if yaml["http.port"] != 2 || yaml["http.tls.cert"] != 4 || yaml["name"] != 5 {
+		t.Fatalf("unexpected yaml lines: %v", yaml)
+	}
+
+	js := configKeyLines([]byte("{\n  \"http\": {\n    \"port\": 80,\n    \"hosts\": [\"a\", \"b\"]\n  },\n  \"name\": \"x\"\n}"), "json")
+	if js["http.port"] != 3 || js["http.hosts"] != 4 || js["name"] != 6 {
+		t.Fatalf("unexpected json lines: %v", js)
+	}
+}
+
+func TestLoadConfigTreeRecordsSources(t *testing.T) {
+	dir := t.TempDir()
+	writeConfigFile(t, filepath.Join(dir, "base.yaml"), "http:\n  host: 0.0.0.0\n  port: 80\n")
+	writeConfigFile(t, filepath.Join(dir, "config.toml"), "include = \"base.yaml\"\n\n[http]\nport = 8080\n")
+	writeConfigFile(t, filepath.Join(dir, "config.d", "10-a.json"), "{\n  \"setting\": {\"name\": \"a\"}\n}")
+
+	_, loader, err := loadConfigTree(filepath.Join(dir, "config.toml"), "", filepath.Join(dir, "config.d"))
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	sources := loader.sources
+	if !strings.HasSuffix(sources["http.port"], "config.toml:4") {
+		t.Fatalf("expected main file to own http.port, got %v", sources)
+	}
+	if !strings.HasSuffix(sources["http.host"], "base.yaml:2") {
+		t.Fatalf("expected include to own http.host, got %v", sources)
+	}
+	if !strings.HasSuffix(sources["setting.name"], "10-a.json:2") {
+		t.Fatalf("expected conf.d file to own setting.name, got %v", sources)
+	}
+}
+
+func TestPrintConfigAnnotatesAndRedacts(t *testing.T) {
+	defer configSecrets.set(nil)
+	configSecrets.set([]string{"db.pass"})
+
+	cfg := Map{
+		"name": "demo",
+		"db":   Map{"pass": "s3cret", "host": "db", "token": "t"},
+		"jobs": []Map{{"id": int64(1)}},
+	}
+	sources := resolveConfigSources(cfg, map[string]string{
+		"name":    "env INFRAGO__NAME",
+		"db":      "config.toml:3",
+		"db.host": "config.toml:5",
+	})
+	if sources["db.pass"] != "config.toml:3" || sources["jobs"] != sourceConfig {
+		t.Fatalf("unexpected resolved sources: %v", sources)
+	}
+
+	out := &bytes.Buffer{}
+	if err := printConfig(out, cfg, sources, "toml"); err != nil {
+		t.Fatalf("print toml: %v", err)
+	}
+	text := out.String()
+	for _, want := range []string{
+		`name = "demo"  # env INFRAGO__NAME`,
+		"[db]",
+		`host = "db"  # config.toml:5`,
+		`pass = "******"`,
+		`token = "******"`,
+		"[[jobs]]",
+	} {
+		if !strings.Contains(text, want) {
+			t.Fatalf("expected %q in:\n%s", want, text)
+		}
+	}
+	if strings.Contains(text, "s3cret") {
+		t.Fatalf("secret leaked:\n%s", text)
+	}
+
+	out.Reset()
+	if err := printConfig(out, cfg, sources, "yaml"); err != nil {
+		t.Fatalf("print yaml: %v", err)
+	}
+	if !strings.Contains(out.String(), "db:\n  host: \"db\"  # config.toml:5") {
+		t.Fatalf("unexpected yaml:\n%s", out.String())
+	}
+
+	out.Reset()
+	if err := printConfig(out, cfg, sources, "json"); err != nil {
+		t.Fatalf("print json: %v", err)
+	}
+	doc := Map{}
+	if err := json.Unmarshal(out.Bytes(), &doc); err != nil {
+		t.Fatalf("decode json: %v", err)
+	}
+	if doc["sources"].(map[string]Any)["name"] != "env INFRAGO__NAME" {
+		t.Fatalf("unexpected json: %s", out.String())
+	}
+
+	if format, ok := printConfigFormat([]string{"--print-config", "yaml"}); !ok || format != "yaml" {
+		t.Fatalf("unexpected format: %q %v", format, ok)
+	}
+	if format, ok := printConfigFormat([]string{"--print-config", "--set", "a=b"}); !ok || format != "toml" {
+		t.Fatalf("expected toml default, got %q %v", format, ok)
+	}
+}
+
+func TestPrintConfigTOMLItemsAndQuotedKeys(t *testing.T) {
+	cfg := Map{
+		"sites": []Map{{"url": "a", "tls": Map{"on": true}}, {"url": "b"}},
+		"odd":   Map{"a\x01b": int64(1), "tab\tkey": int64(2), `q"k`: int64(3)},
+	}
+	sources := resolveConfigSources(cfg, map[string]string{
+		"sites":       "config.toml:7",
+		"sites.1.url": "flag --set sites.1.url",
+	})
+
+	out := &bytes.Buffer{}
+	if err := printConfig(out, cfg, sources, "toml"); err != nil {
+		t.Fatalf("print toml: %v", err)
+	}
+	text := out.String()
+	for _, want := range []string{
+		`[[sites]]  # config.toml:7`,
+		`url = "a"  # config.toml:7`,
+		`url = "b"  # flag --set sites.1.url`,
+		`[sites.tls]`,
+		`on = true  # config.toml:7`,
+		`"a\u0001b" = 1`,
+		`"tab\tkey" = 2`,
+		`"q\"k" = 3`,
+	} {
+		if !strings.Contains(text, want) {
+			t.Fatalf("expected %q in:\n%s", want, text)
+		}
+	}
+	if strings.Contains(text, `\x01`) {
+		t.Fatalf("expected TOML escapes, got:\n%s", text)
+	}
+}
@@ -0,0 +1,179 @@
+package infra
+
+import (
+	"log/slog"
+	"os"
+	"os/signal"
+	"reflect"
+	"sort"
+	"sync"
+	"syscall"
+
+	. "github.com/infrago/base"
+)
+
+var (
+	reloader = &reloadModule{}
+)
+
+type (
+	// Reconfigurer is implemented by modules that can apply config changes
+	// after Setup, it receives the whole reloaded config.
+	Reconfigurer interface {
+		Reconfigure(Map)
+	}
+
+	// reloadModule reloads config on SIGHUP and when the config hook reports a change.
+	reloadModule struct {
+		mutex   sync.Mutex
+		reload  sync.Mutex
+		signals chan os.Signal
+		done    chan struct{}
+		wg      sync.WaitGroup
+	}
+)
+
+func (m *reloadModule) Register(string, Any) {}
+func (m *reloadModule) Config(Map)           {}
+func (m *reloadModule) Setup()               {}
+func (m *reloadModule) Open()                {}
+func (m *reloadModule) Close()               {}
+
+func (m *reloadModule) Start() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if m.done != nil {
+		return
+	}
+
+	m.done = make(chan struct{})
+	m.signals = make(chan os.Signal, 1)
+	signal.Notify(m.signals, syscall.SIGHUP)
+
+	done, signals := m.done, m.signals
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		for {
+			select {
+			case <-done:
+				return
+			case <-signals:
+				m.trigger("signal")
+			}
+		}
+	}()
+
+	if watcher, ok := hook.configWatcher(); ok {
+		m.wg.Add(1)
+		go func() {
+			defer m.wg.Done()
+			watcher.WatchConfig(done, func() { m.trigger("watch") })
+		}()
+	}
+}
+
+func (m *reloadModule) Stop() {
+	m.mutex.Lock()
+	if m.done == nil {
+		m.mutex.Unlock()
+		return
+	}
+	signal.Stop(m.signals)
+	close(m.done)
+	m.done, m.signals = nil, nil
+	m.mutex.Unlock()
+	m.wg.Wait()
+}
+
+// trigger reloads and logs a failure, the running config stays in place.
+func (m *reloadModule) trigger(source string) {
+	if _, err := infrago.Reload(); err != nil {
+		hook.Log(nil, slog.LevelWarn, "config reload failed", "source", source, "error", err)
+	}
+}
+
+// Reload loads config again and applies what changed since the last load.
+// Setting is swapped as a whole, then Reconfigurer modules are called and
+// the CONFIG_CHANGED trigger fires. It returns the changed keys.
+func (c *infragoRuntime) Reload() ([]string, error) {
+	reloader.reload.Lock()
+	defer reloader.reload.Unlock()
+
+	if err := loadSecretKey(); err != nil {
+		return nil, err
+	}
+	loaded, err := c.prepareConfig()
+	if err != nil {
+		return nil, err
+	}
+	cfg := loaded.cfg
+	configSecrets.set(loaded.secrets)
+
+	c.mutex.Lock()
+	c.sources = loaded.sources
+	keys := diffConfig(c.loaded, cfg)
+	if len(keys) == 0 {
+		c.mutex.Unlock()
+		return nil, nil
+	}
+	setting := cloneSettingMap(c.baseSetting)
+	if next, ok := cfg["setting"].(Map); ok {
+		setting = mergeMap(setting, next)
+	}
+	c.setting = setting
+	c.loaded = cloneSettingMap(cfg)
+	c.mutex.Unlock()
+
+	for _, mod := range c.moduleList() {
+		if r, ok := mod.(Reconfigurer); ok {
+			c.lifecycle("reconfigure", mod, func() { r.Reconfigure(cfg) })
+		}
+	}
+	trigger.Toggle(CONFIG_CHANGED, Map{"keys": keys})
+	hook.Log(nil, slog.LevelInfo, "config reloaded", "keys", keys)
+	return keys, nil
+}
+
+// diffConfig lists dotted keys whose values differ between old and next, sorted.
+func diffConfig(old, next Map) []string {
+	changed := map[string]struct{}{}
+	diffConfigMap("", old, next, changed)
+	keys := make([]string, 0, len(changed))
+	for key := range changed {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func diffConfigMap(prefix string, old, next Map, changed map[string]struct{}) {
+	for key, value := range old {
+		diffConfigValue(prefix+key, value, next[key], changed)
+	}
+	for key, value := range next {
+		if _, ok := old[key]; !ok {
+			diffConfigValue(prefix+key, nil, value, changed)
+		}
+	}
+}
+
+func diffConfigValue(key string, old, next Any, changed map[string]struct{}) {
+	oldMap, oldOk := old.(Map)
+	nextMap, nextOk := next.(Map)
+	if oldOk || nextOk {
+		if !oldOk {
+			oldMap = Map{}
+		}
+		if !nextOk {
+			nextMap = Map{}
+		}
+		if (old == nil || oldOk) && (next == nil || nextOk) {
+			diffConfigMap(key+".", oldMap, nextMap, changed)
+			return
+		}
+	}
+	if !reflect.DeepEqual(old, next) {
+		changed[key] = struct{}{}
+	}
+}
@@ -0,0 +1,122 @@
+package infra
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	. "github.com/infrago/base"
+)
+
+type reloadTestConfig struct {
+	cfg Map
+}
+
+func (h *reloadTestConfig) LoadConfig() (Map, error) {
+	return cloneSettingMap(h.cfg), nil
+}
+
+type reloadTestModule struct {
+	got []Map
+}
+
+func (m *reloadTestModule) Register(string, Any) {}
+func (m *reloadTestModule) Config(Map)           {}
+func (m *reloadTestModule) Setup()               {}
+func (m *reloadTestModule) Open()                {}
+func (m *reloadTestModule) Start()               {}
+func (m *reloadTestModule) Stop()                {}
+func (m *reloadTestModule) Close()               {}
+
+func (m *reloadTestModule) Reconfigure(cfg Map) {
+	m.got = append(m.got, cfg)
+}
+
+func TestDiffConfigListsChangedLeaves(t *testing.T) {
+	old := Map{"http": Map{"port": int64(80), "host": "a"}, "setting": Map{"name": "x"}, "gone": true}
+	next := Map{"http": Map{"port": int64(8080), "host": "a"}, "setting": Map{"name": "x", "region": "eu"}, "log": Map{"level": "info"}}
+
+	keys := diffConfig(old, next)
+	want := []string{"gone", "http.port", "log.level", "setting.region"}
+	if !reflect.DeepEqual(keys, want) {
+		t.Fatalf("expected %v, got %v", want, keys)
+	}
+	if keys := diffConfig(next, cloneSettingMap(next)); len(keys) != 0 {
+		t.Fatalf("expected no change, got %v", keys)
+	}
+}
+
+func TestReloadSwapsSettingAndNotifiesModules(t *testing.T) {
+	originalHook := hook
+	source := &reloadTestConfig{cfg: Map{"setting": Map{"name": "a", "keep": int64(1)}}}
+	hook = &infragoHook{}
+	hook.AttachConfig(source)
+	defer func() { hook = originalHook }()
+
+	mod := &reloadTestModule{}
+	c := &infragoRuntime{setting: Map{"base": true}, modules: []Module{mod}}
+	c.baseSetting = cloneSettingMap(c.setting)
+	c.loaded = cloneSettingMap(source.cfg)
+
+	if keys, err := c.Reload(); err != nil || len(keys) != 0 || len(mod.got) != 0 {
+		t.Fatalf("expected no-op reload, got %v %v %d", keys, err, len(mod.got))
+	}
+
+	source.cfg = Map{"setting": Map{"name": "b"}}
+	keys, err := c.Reload()
+	if err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	if !reflect.DeepEqual(keys, []string{"setting.keep", "setting.name"}) {
+		t.Fatalf("unexpected keys: %v", keys)
+	}
+	setting := c.Setting()
+	if setting["name"] != "b" || setting["base"] != true || setting["keep"] != nil {
+		t.Fatalf("unexpected setting: %#v", setting)
+	}
+	if len(mod.got) != 1 || mod.got[0]["setting"].(Map)["name"] != "b" {
+		t.Fatalf("expected one Reconfigure call, got %#v", mod.got)
+	}
+}
+
+func TestDefaultConfigHookWatchesFiles(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "config.toml")
+	writeConfigFile(t, file, "[setting]\nname = \"a\"\n")
+
+	h := &defaultConfigHook{}
+	if _, loader, confDir, err := loadConfigFiles(Map{"file": file}); err != nil {
+		t.Fatalf("load: %v", err)
+	} else {
+		h.files, h.confDir = loader.files, confDir
+	}
+	h.watch = 10 * time.Millisecond
+
+	done := make(chan struct{})
+	changed := make(chan struct{}, 1)
+	go h.WatchConfig(done, func() {
+		select {
+		case changed <- struct{}{}:
+		default:
+		}
+	})
+	defer close(done)
+
+	time.Sleep(30 * time.Millisecond)
+	writeConfigFile(t, filepath.Join(dir, "config.d", "10-extra.toml"), "[setting]\nregion = \"eu\"\n")
+	select {
+	case <-changed:
+	case <-time.After(2 * time.Second):
+		t.Fatalf("expected change from new config.d file")
+	}
+}
+
+func TestConfigWatchInterval(t *testing.T) {
+	cases := map[Any]time.Duration{nil: 0, "false": 0, "true": defaultConfigWatch, "500ms": 500 * time.Millisecond, "bad": 0}
+	for in, want := range cases {
+		if got := configWatchInterval(in); got != want {
+			t.Fatalf("configWatchInterval(%v) = %v, want %v", in, got, want)
+		}
+	}
+}
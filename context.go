@@ -2,6 +2,7 @@ package infra
 
 import (
 	"context"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -11,8 +12,9 @@ import (
 
 type (
 	Meta struct {
-		mutex sync.RWMutex
-		ctx   context.Context
+		mutex  sync.RWMutex
+		ctx    context.Context
+		cancel context.CancelFunc
 
 		traceId      string
 		spanId       string
@@ -20,9 +22,17 @@ type (
 		traceKind    string
 		traceEntry   string
 
-		language string
-		timezone int
-		token    string
+		traceFlags    byte
+		traceFlagsSet bool
+		traceState    string
+
+		language    string
+		zone        string
+		timezone    int
+		timezoneSet bool
+		loc         *time.Location
+		token       string
+		baggage     map[string]string
 
 		result Res
 
@@ -32,15 +42,21 @@ type (
 		tokenAuth  bool
 
 		spanStack []metaSpanFrame
+		span      TraceSpan
 	}
 
 	Metadata struct {
-		TraceId      string `json:"tid,omitempty"`
-		SpanId       string `json:"sid,omitempty"`
-		ParentSpanId string `json:"psid,omitempty"`
-		Language     string `json:"l,omitempty"`
-		Timezone     int    `json:"z,omitempty"`
-		Token        string `json:"t,omitempty"`
+		TraceId      string            `json:"tid,omitempty"`
+		SpanId       string            `json:"sid,omitempty"`
+		ParentSpanId string            `json:"psid,omitempty"`
+		TraceFlags   string            `json:"tf,omitempty"`
+		TraceState   string            `json:"ts,omitempty"`
+		Language     string            `json:"l,omitempty"`
+		Timezone     int               `json:"z,omitempty"`
+		Zone         string            `json:"zn,omitempty"`
+		Token        string            `json:"t,omitempty"`
+		Baggage      map[string]string `json:"bg,omitempty"`
+		Deadline     int64             `json:"dl,omitempty"`
 	}
 
 	metaSpanFrame struct {
@@ -52,25 +68,76 @@ type (
 )
 
 func NewMeta() *Meta {
-	return &Meta{ctx: context.Background(), spanStack: make([]metaSpanFrame, 0, 8)}
+	m := &Meta{spanStack: make([]metaSpanFrame, 0, 8)}
+	m.ctx = ContextWithMeta(context.Background(), m)
+	return m
 }
 
+// WithContext replaces the context of meta, the new context carries meta too.
 func (m *Meta) WithContext(ctx context.Context) *Meta {
-	if ctx == nil {
-		ctx = context.Background()
-	}
-	m.ctx = ctx
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.ctx = ContextWithMeta(ctx, m)
 	return m
 }
 
+// Context returns a context carrying meta, see MetaFromContext.
 func (m *Meta) Context() context.Context {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
 	if m.ctx == nil {
-		return context.Background()
+		return ContextWithMeta(context.Background(), m)
 	}
 	return m.ctx
 }
 
+// Fork returns a child meta for concurrent work started from m.
+// The child shares trace context, deadline, language, timezone, token and
+// baggage, but has its own result and span stack, so parallel invokes from
+// one handler do not interleave their spans.
+func (m *Meta) Fork() *Meta {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	child := &Meta{
+		traceId:       m.traceId,
+		spanId:        m.spanId,
+		parentSpanId:  m.parentSpanId,
+		traceKind:     m.traceKind,
+		traceEntry:    m.traceEntry,
+		traceFlags:    m.traceFlags,
+		traceFlagsSet: m.traceFlagsSet,
+		traceState:    m.traceState,
+		language:      m.language,
+		zone:          m.zone,
+		timezone:      m.timezone,
+		timezoneSet:   m.timezoneSet,
+		loc:           m.loc,
+		token:         m.token,
+		payload:       m.payload,
+		tokenId:       m.tokenId,
+		tokenValid:    m.tokenValid,
+		tokenAuth:     m.tokenAuth,
+		spanStack:     make([]metaSpanFrame, 0, 8),
+		span:          m.span,
+	}
+	if len(m.baggage) > 0 {
+		child.baggage = make(map[string]string, len(m.baggage))
+		for k, v := range m.baggage {
+			child.baggage[k] = v
+		}
+	}
+	parent := m.ctx
+	if parent == nil {
+		parent = context.Background()
+	}
+	child.ctx = ContextWithMeta(parent, child)
+	return child
+}
+
 func (m *Meta) TraceId(id ...string) string {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
 	if len(id) > 0 {
 		m.traceId = id[0]
 	}
@@ -78,6 +145,8 @@ func (m *Meta) TraceId(id ...string) string {
 }
 
 func (m *Meta) SpanId(id ...string) string {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
 	if len(id) > 0 {
 		m.spanId = id[0]
 	}
@@ -85,6 +154,8 @@ func (m *Meta) SpanId(id ...string) string {
 }
 
 func (m *Meta) ParentSpanId(id ...string) string {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
 	if len(id) > 0 {
 		m.parentSpanId = id[0]
 	}
@@ -92,6 +163,8 @@ func (m *Meta) ParentSpanId(id ...string) string {
 }
 
 func (m *Meta) TraceKind(kind ...string) string {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
 	if len(kind) > 0 {
 		m.traceKind = kind[0]
 	}
@@ -99,6 +172,8 @@ func (m *Meta) TraceKind(kind ...string) string {
 }
 
 func (m *Meta) TraceEntry(entry ...string) string {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
 	if len(entry) > 0 {
 		m.traceEntry = entry[0]
 	}
@@ -130,31 +205,140 @@ func (m *Meta) PopSpanFrame() (string, string, bool) {
 	return last.prevSpanId, last.prevParentId, true
 }
 
-// ParseTraceParent parses W3C traceparent: 00-<traceid>-<spanid>-<flags>.
+// enterSpan saves the current span and moves meta to a new child span in one step.
+// Empty kind or entry keep the current ones.
+func (m *Meta) enterSpan(kind, entry string) (string, string, string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.spanStack = append(m.spanStack, metaSpanFrame{
+		prevSpanId:   m.spanId,
+		prevParentId: m.parentSpanId,
+		prevKind:     m.traceKind,
+		prevEntry:    m.traceEntry,
+	})
+	if m.traceId == "" {
+		m.traceId = newTraceID()
+	}
+	m.parentSpanId = m.spanId
+	m.spanId = newSpanID()
+	if kind != "" {
+		m.traceKind = kind
+	}
+	if entry != "" {
+		m.traceEntry = entry
+	}
+	return m.traceId, m.spanId, m.parentSpanId
+}
+
+// leaveSpan restores the span saved by the matching enterSpan.
+func (m *Meta) leaveSpan() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	size := len(m.spanStack)
+	if size == 0 {
+		return
+	}
+	last := m.spanStack[size-1]
+	m.spanStack = m.spanStack[:size-1]
+	m.spanId = last.prevSpanId
+	m.parentSpanId = last.prevParentId
+	m.traceKind = last.prevKind
+	m.traceEntry = last.prevEntry
+}
+
+// ParseTraceParent parses W3C traceparent: <version>-<traceid>-<spanid>-<flags>.
+// Version ff, all-zero ids and malformed fields are rejected. Future versions
+// are accepted with trailing fields, only their sampled bit is honored.
 func (m *Meta) ParseTraceParent(traceparent string) bool {
 	traceparent = strings.TrimSpace(traceparent)
-	if traceparent == "" {
+	if len(traceparent) < 55 {
 		return false
 	}
 	parts := strings.Split(traceparent, "-")
-	if len(parts) != 4 {
+	if len(parts) < 4 {
+		return false
+	}
+	version, traceId, spanId, flags := parts[0], parts[1], parts[2], parts[3]
+	if len(version) != 2 || !isLowerHexString(version) || version == "ff" {
+		return false
+	}
+	if version == "00" && len(parts) != 4 {
+		return false
+	}
+	if len(traceId) != 32 || !isLowerHexString(traceId) || isZeroHexString(traceId) {
+		return false
+	}
+	if len(spanId) != 16 || !isLowerHexString(spanId) || isZeroHexString(spanId) {
 		return false
 	}
-	traceId := strings.TrimSpace(parts[1])
-	spanId := strings.TrimSpace(parts[2])
-	if len(traceId) != 32 || len(spanId) != 16 || !isHexString(traceId) || !isHexString(spanId) {
+	if len(flags) != 2 || !isLowerHexString(flags) {
 		return false
 	}
-	m.TraceId(traceId)
-	m.ParentSpanId(spanId)
+	value := byte(unhexByte(flags[0])<<4 | unhexByte(flags[1]))
+	if version != "00" {
+		value &= traceFlagSampled
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.traceId = traceId
+	m.parentSpanId = spanId
+	m.traceFlags, m.traceFlagsSet = value, true
 	return true
 }
 
-// TraceParent builds W3C traceparent using current trace/span ids.
+// TraceParent builds W3C traceparent using current trace/span ids and flags.
 func (m *Meta) TraceParent() string {
-	traceId := normalizeHexID(m.TraceId(), 32)
-	spanId := normalizeHexID(m.SpanId(), 16)
-	return "00-" + traceId + "-" + spanId + "-01"
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	traceId := normalizeHexID(m.traceId, 32)
+	spanId := normalizeHexID(m.spanId, 16)
+	return "00-" + traceId + "-" + spanId + "-" + hexByte(m.flags())
+}
+
+// TraceFlags gets or sets W3C trace flags, unset flags default to sampled.
+func (m *Meta) TraceFlags(flags ...byte) byte {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if len(flags) > 0 {
+		m.traceFlags = flags[0]
+		m.traceFlagsSet = true
+	}
+	return m.flags()
+}
+
+// flags returns the effective trace flags, callers hold the mutex.
+func (m *Meta) flags() byte {
+	if !m.traceFlagsSet {
+		return traceFlagSampled
+	}
+	return m.traceFlags
+}
+
+// Sampled gets or sets the sampled bit of trace flags.
+func (m *Meta) Sampled(sampled ...bool) bool {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if len(sampled) > 0 {
+		flags := m.flags()
+		if sampled[0] {
+			flags |= traceFlagSampled
+		} else {
+			flags &^= traceFlagSampled
+		}
+		m.traceFlags, m.traceFlagsSet = flags, true
+	}
+	return m.flags()&traceFlagSampled != 0
+}
+
+// TraceState gets or sets W3C tracestate, an invalid value clears it.
+func (m *Meta) TraceState(state ...string) string {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if len(state) > 0 {
+		m.traceState, _ = parseTraceState(state[0])
+	}
+	return m.traceState
 }
 
 func normalizeHexID(raw string, size int) string {
@@ -182,6 +366,24 @@ func normalizeHexID(raw string, size int) string {
 	return raw
 }
 
+func isLowerHexString(v string) bool {
+	for i := 0; i < len(v); i++ {
+		if !((v[i] >= '0' && v[i] <= '9') || (v[i] >= 'a' && v[i] <= 'f')) {
+			return false
+		}
+	}
+	return true
+}
+
+func isZeroHexString(v string) bool {
+	return strings.Trim(v, "0") == ""
+}
+
+func hexByte(b byte) string {
+	const hex = "0123456789abcdef"
+	return string([]byte{hex[b>>4], hex[b&0x0f]})
+}
+
 func isHexString(v string) bool {
 	for _, r := range v {
 		if !((r >= '0' && r <= '9') || (r >= 'a' && r <= 'f') || (r >= 'A' && r <= 'F')) {
@@ -192,6 +394,8 @@ func isHexString(v string) bool {
 }
 
 func (m *Meta) Language(v ...string) string {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
 	if len(v) > 0 {
 		m.language = v[0]
 	}
@@ -202,18 +406,107 @@ func (m *Meta) Language(v ...string) string {
 func (m *Meta) String(key string, args ...Any) string {
 	return String(m.Language(), key, args...)
 }
+
+// Timezone gets or sets the timezone of meta.
+// Named zones like Europe/Berlin keep their daylight saving rules, unnamed
+// zones fall back to a fixed offset, time.Local or nil clears the setting.
+// An unset timezone returns time.Local, UTC is always explicit.
 func (m *Meta) Timezone(zones ...*time.Location) *time.Location {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
 	if len(zones) > 0 {
-		_, offset := time.Now().In(zones[0]).Zone()
-		m.timezone = offset
+		m.setTimezone(zones[0])
 	}
-	if m.timezone == 0 {
+	return m.location()
+}
+
+// Zone gets or sets the IANA zone name, an unknown name leaves meta unchanged.
+// Unnamed fixed offsets return an empty name.
+func (m *Meta) Zone(name ...string) string {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if len(name) > 0 {
+		if loc, ok := loadZone(name[0]); ok {
+			m.setTimezone(loc)
+		}
+	}
+	return m.zone
+}
+
+// setTimezone stores loc for local use, and the IANA name or else the current
+// offset of loc for Metadata. Callers hold the mutex.
+func (m *Meta) setTimezone(loc *time.Location) {
+	if loc == nil || loc == time.Local {
+		m.zone, m.timezone, m.timezoneSet, m.loc = "", 0, false, nil
+		return
+	}
+	_, offset := time.Now().In(loc).Zone()
+	m.zone, m.timezone, m.timezoneSet, m.loc = "", offset, true, loc
+	if name := loc.String(); name != "" {
+		if zone, ok := loadZone(name); ok && sameZone(loc, zone) {
+			m.zone = name
+		}
+	} else if offset == 0 {
+		m.zone = "UTC"
+	}
+}
+
+// sameZone compares offsets in winter and summer, so time.FixedZone("CET", 3600)
+// is not mistaken for the IANA CET zone, which has DST.
+func sameZone(a, b *time.Location) bool {
+	year := time.Now().Year()
+	for _, month := range []time.Month{time.January, time.July} {
+		at := time.Date(year, month, 1, 12, 0, 0, 0, time.UTC)
+		_, left := at.In(a).Zone()
+		_, right := at.In(b).Zone()
+		if left != right {
+			return false
+		}
+	}
+	return true
+}
+
+// location resolves the stored timezone, callers hold the mutex.
+func (m *Meta) location() *time.Location {
+	if m.loc != nil {
+		return m.loc
+	}
+	if m.zone != "" {
+		if loc, ok := loadZone(m.zone); ok {
+			return loc
+		}
+	}
+	if !m.timezoneSet {
 		return time.Local
 	}
+	if m.timezone == 0 {
+		return time.UTC
+	}
 	return time.FixedZone("", m.timezone)
 }
 
+var zoneCache sync.Map
+
+// loadZone loads an IANA zone once, "Local" is not a portable name and is rejected.
+func loadZone(name string) (*time.Location, bool) {
+	name = strings.TrimSpace(name)
+	if name == "" || name == "Local" {
+		return nil, false
+	}
+	if cached, ok := zoneCache.Load(name); ok {
+		return cached.(*time.Location), true
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, false
+	}
+	zoneCache.Store(name, loc)
+	return loc, true
+}
+
 func (m *Meta) Token(v ...string) string {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
 	if len(v) > 0 {
 		m.token = v[0]
 		m.clearTokenState()
@@ -223,20 +516,25 @@ func (m *Meta) Token(v ...string) string {
 
 // Verify validates token signature and payload.
 func (m *Meta) Verify(token string) error {
+	m.mutex.Lock()
+	m.token = token
+	m.clearTokenState()
+	m.mutex.Unlock()
 	if token == "" {
-		m.token = ""
-		m.clearTokenState()
 		return nil
 	}
 
-	m.token = token
-	m.clearTokenState()
-
 	session, err := hook.VerifyToken(token)
 	if err != nil {
 		return err
 	}
 
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if m.token != token {
+		// replaced while verifying.
+		return nil
+	}
 	m.tokenId = session.TokenID
 	m.payload = session.Payload
 	m.tokenAuth = session.Auth
@@ -254,7 +552,7 @@ func (m *Meta) Sign(auth bool, payload Map, expires ...time.Duration) string {
 // expires is optional duration.
 func (m *Meta) SignAt(auth bool, payload Map, begin time.Time, expires ...time.Duration) string {
 	beginUnix, expireUnix := tokenTimeWindow(begin, expires...)
-	tokenID := m.tokenId
+	tokenID := m.TokenId()
 	if tokenID == "" {
 		tokenID = GenerateTokenID()
 	}
@@ -273,6 +571,8 @@ func (m *Meta) SignAt(auth bool, payload Map, begin time.Time, expires ...time.D
 	if req.Payload == nil {
 		req.Payload = Map{}
 	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
 	m.token = token
 	m.tokenId = req.TokenID
 	m.payload = req.Payload
@@ -306,6 +606,8 @@ func (m *Meta) NewSignAt(auth bool, payload Map, begin time.Time, expires ...tim
 	if req.Payload == nil {
 		req.Payload = Map{}
 	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
 	m.token = token
 	m.tokenId = req.TokenID
 	m.payload = req.Payload
@@ -346,6 +648,8 @@ func (m *Meta) RevokeTokenID(tokenID string, expires ...int64) error {
 
 // Signed returns whether token is valid.
 func (m *Meta) Signed() bool {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
 	return m.tokenValid
 }
 
@@ -356,6 +660,8 @@ func (m *Meta) Unsigned() bool {
 
 // Authed returns whether token is valid and auth flag is true.
 func (m *Meta) Authed() bool {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
 	return m.tokenValid && m.tokenAuth
 }
 
@@ -366,11 +672,15 @@ func (m *Meta) Unauthed() bool {
 
 // TokenId returns token id placeholder.
 func (m *Meta) TokenId() string {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
 	return m.tokenId
 }
 
 // Payload returns token payload placeholder.
 func (m *Meta) Payload() Map {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
 	if m.payload == nil {
 		return Map{}
 	}
@@ -378,6 +688,8 @@ func (m *Meta) Payload() Map {
 }
 
 func (m *Meta) Result(res ...Res) Res {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
 	if len(res) > 0 {
 		m.result = res[0]
 		return res[0]
@@ -392,36 +704,100 @@ func (m *Meta) Result(res ...Res) Res {
 	return ret
 }
 
+// Metadata exports meta for the bus, or restores meta from data received from it.
+// Restoring a deadline starts a timer on meta, a bus hook that restores metadata
+// must call Finish once the handler returns, as dispatchService does.
 func (m *Meta) Metadata(data ...Metadata) Metadata {
 	if len(data) > 0 {
 		d := data[0]
+		state, _ := parseTraceState(d.TraceState)
+		m.mutex.Lock()
 		m.traceId = d.TraceId
 		m.spanId = d.SpanId
 		m.parentSpanId = d.ParentSpanId
+		m.traceFlags, m.traceFlagsSet = 0, false
+		if len(d.TraceFlags) == 2 && isLowerHexString(d.TraceFlags) {
+			m.traceFlags, m.traceFlagsSet = unhexByte(d.TraceFlags[0])<<4|unhexByte(d.TraceFlags[1]), true
+		}
+		m.traceState = state
 		m.language = d.Language
-		m.timezone = d.Timezone
-		m.Token(d.Token)
-		if d.Token != "" {
-			_ = m.Verify(d.Token)
+		m.zone, m.timezone, m.timezoneSet, m.loc = "", 0, false, nil
+		if loc, ok := loadZone(d.Zone); ok {
+			m.setTimezone(loc)
+		} else if d.Timezone != 0 {
+			m.timezone, m.timezoneSet = d.Timezone, true
+		}
+		m.baggage = nil
+		m.mutex.Unlock()
+
+		_ = m.Verify(d.Token)
+		// sorted, so the same items survive the limits on every node.
+		keys := make([]string, 0, len(d.Baggage))
+		for k := range d.Baggage {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			m.Baggage(k, d.Baggage[k])
+		}
+		if d.Deadline > 0 {
+			m.WithDeadline(time.UnixMilli(d.Deadline))
 		}
 	}
 
+	deadline := int64(0)
+	if vv, ok := m.Deadline(); ok {
+		deadline = vv.UnixMilli()
+	}
+
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	flags := ""
+	if m.traceFlagsSet {
+		flags = hexByte(m.traceFlags)
+	}
 	return Metadata{
 		TraceId:      m.traceId,
 		SpanId:       m.spanId,
 		ParentSpanId: m.parentSpanId,
+		TraceFlags:   flags,
+		TraceState:   m.traceState,
 		Language:     m.language,
 		Timezone:     m.timezone,
+		Zone:         m.zone,
 		Token:        m.token,
+		Baggage:      outboundBaggage(m.baggage),
+		Deadline:     deadline,
 	}
 }
 
 // Begin starts a trace span through trace hook.
+// Unsampled traces still get span ids for propagation but are not recorded.
 func (m *Meta) Begin(name string, attrs ...Map) TraceSpan {
+	if !m.Sampled() {
+		return beginUnsampledSpan(m)
+	}
 	merged := mergeMetaAttrs(attrs...)
 	return hook.Begin(m, name, merged)
 }
 
+// Span returns the span core opened for the current invocation.
+// It is never nil, annotations are dropped outside an invocation.
+func (m *Meta) Span() *Span {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return &Span{span: m.span}
+}
+
+// swapSpan makes span current and returns the previous one.
+func (m *Meta) swapSpan(span TraceSpan) TraceSpan {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	prev := m.span
+	m.span = span
+	return prev
+}
+
 // Trace emits one trace event through trace hook.
 func (m *Meta) Trace(name string, attrs ...Map) error {
 	merged := mergeMetaAttrs(attrs...)
@@ -441,7 +817,7 @@ func (m *Meta) Invoke(name string, values ...Map) Map {
 		value = values[0]
 	}
 	data, res := core.Invoke(m, name, value)
-	m.result = res
+	m.Result(res)
 	return data
 }
 
@@ -452,27 +828,47 @@ func (m *Meta) Execute(name string, values ...Map) Map {
 		value = values[0]
 	}
 	data, res := core.Execute(m, name, value)
-	m.result = res
+	m.Result(res)
 	return data
 }
 
 // Request calls remote service only.
 func (m *Meta) Request(name string, value Map, timeout ...time.Duration) Map {
 	data, res := core.Request(m, name, value, timeout...)
-	m.result = res
+	m.Result(res)
 	return data
 }
 
+// Dispatch enqueues one service job under a producer span.
 func (m *Meta) Dispatch(name string, value Map) error {
-	return hook.Dispatch(name, value, m)
+	span := m.beginProducerSpan(TraceKindQueue, "dispatch", name)
+	err := hook.Dispatch(name, value, m)
+	span.End(err)
+	return err
 }
 
+// Broadcast sends one event to all subscribers under a producer span.
 func (m *Meta) Broadcast(name string, value Map) error {
-	return hook.Broadcast(name, value, m)
+	span := m.beginProducerSpan(TraceKindEvent, "broadcast", name)
+	err := hook.Broadcast(name, value, m)
+	span.End(err)
+	return err
 }
 
+// Rolecast sends one event to one node per role under a producer span.
 func (m *Meta) Rolecast(name string, value Map) error {
-	return hook.Rolecast(name, value, m)
+	span := m.beginProducerSpan(TraceKindEvent, "rolecast", name)
+	err := hook.Rolecast(name, value, m)
+	span.End(err)
+	return err
+}
+
+func (m *Meta) beginProducerSpan(kind, operation, name string) TraceSpan {
+	return m.Begin(kind+":"+name, TraceAttrs("infrago", kind, name, Map{
+		"module":    "bus",
+		"operation": operation,
+		"step":      "producer",
+	}))
 }
 
 // Enqueue is compatibility alias of Dispatch.
@@ -515,6 +911,8 @@ func (m *Meta) Invoking(name string, offset, limit int, values ...Map) (int64, [
 // InvokeOK executes one call and returns whether result is OK.
 func (m *Meta) InvokeOK(name string, values ...Map) bool {
 	_ = m.Invoke(name, values...)
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
 	return m.result == nil || m.result.OK()
 }
 
@@ -536,6 +934,7 @@ func mergeMetaAttrs(items ...Map) Map {
 	return out
 }
 
+// clearTokenState drops verified token state, callers hold the mutex.
 func (m *Meta) clearTokenState() {
 	m.tokenValid = false
 	m.tokenAuth = false
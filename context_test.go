@@ -0,0 +1,142 @@
+package infra
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMetaForkSharesContextNotState(t *testing.T) {
+	parent := NewMeta()
+	parent.TraceId("4bf92f3577b34da6a3ce929d0e0e4736")
+	parent.SpanId("00f067aa0ba902b7")
+	parent.Language("zh-CN")
+	parent.Baggage("tenant", "demo")
+	parent.WithDeadline(time.Now().Add(time.Minute))
+	parent.Result(Fail)
+
+	child := parent.Fork()
+	if child.TraceId() != parent.TraceId() || child.SpanId() != parent.SpanId() || child.Language() != "zh-CN" {
+		t.Fatalf("expected child to share trace context")
+	}
+	if child.Baggage("tenant") != "demo" {
+		t.Fatalf("expected child to share baggage")
+	}
+	if _, ok := child.Deadline(); !ok {
+		t.Fatalf("expected child to inherit deadline")
+	}
+	if MetaFromContext(child.Context()) != child {
+		t.Fatalf("expected child context to carry child")
+	}
+	if child.Result() != OK {
+		t.Fatalf("expected child to have its own result")
+	}
+
+	child.Baggage("tenant", "other")
+	if parent.Baggage("tenant") != "demo" {
+		t.Fatalf("expected child baggage changes to stay local")
+	}
+
+	span := child.Begin("child")
+	if child.ParentSpanId() != parent.SpanId() || parent.SpanId() != "00f067aa0ba902b7" {
+		t.Fatalf("expected child span under parent span without moving parent")
+	}
+	span.End()
+	if parent.Result() != Fail {
+		t.Fatalf("expected parent result to be kept")
+	}
+}
+
+func TestMetaConcurrentUse(t *testing.T) {
+	meta := NewMeta()
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				meta.Baggage("k", "v")
+				meta.Result(OK)
+				_ = meta.Metadata()
+				_ = meta.TraceParent()
+				meta.Fork().Begin("work").End()
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestMetaNamedTimezone(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("tzdata unavailable: %v", err)
+	}
+
+	meta := NewMeta()
+	if meta.Timezone() != time.Local {
+		t.Fatalf("expected unset timezone to be local")
+	}
+	meta.Timezone(berlin)
+	if meta.Zone() != "Europe/Berlin" || meta.Timezone().String() != "Europe/Berlin" {
+		t.Fatalf("expected named zone, got %q", meta.Zone())
+	}
+
+	summer := time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC).In(meta.Timezone())
+	winter := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC).In(meta.Timezone())
+	if summer.Hour() != 14 || winter.Hour() != 13 {
+		t.Fatalf("expected daylight saving aware zone, got %v %v", summer, winter)
+	}
+
+	remote := NewMeta()
+	remote.Metadata(meta.Metadata())
+	if remote.Zone() != "Europe/Berlin" {
+		t.Fatalf("expected zone to cross metadata, got %q", remote.Zone())
+	}
+}
+
+func TestMetaExplicitUTCAndOffset(t *testing.T) {
+	meta := NewMeta()
+	meta.Timezone(time.UTC)
+	if meta.Timezone() != time.UTC || meta.Zone() != "UTC" {
+		t.Fatalf("expected explicit utc, got %v", meta.Timezone())
+	}
+	remote := NewMeta()
+	remote.Metadata(meta.Metadata())
+	if remote.Timezone() != time.UTC {
+		t.Fatalf("expected utc across metadata, got %v", remote.Timezone())
+	}
+
+	// peers that only send an offset still work.
+	legacy := NewMeta()
+	legacy.Metadata(Metadata{Timezone: 3600})
+	if _, offset := time.Now().In(legacy.Timezone()).Zone(); offset != 3600 || legacy.Zone() != "" {
+		t.Fatalf("expected fixed offset fallback, got %v", legacy.Timezone())
+	}
+
+	meta.Timezone(time.Local)
+	if meta.Timezone() != time.Local || meta.Zone() != "" {
+		t.Fatalf("expected local to clear timezone")
+	}
+}
+
+func TestMetaFixedZoneKeepsOffset(t *testing.T) {
+	if _, err := time.LoadLocation("CET"); err != nil {
+		t.Skipf("tzdata unavailable: %v", err)
+	}
+
+	meta := NewMeta()
+	meta.Timezone(time.FixedZone("CET", 3600))
+	if meta.Zone() != "" {
+		t.Fatalf("fixed offset must not become the IANA CET zone, got %q", meta.Zone())
+	}
+	summer := time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC)
+	if summer.In(meta.Timezone()).Hour() != 13 {
+		t.Fatalf("expected fixed +1h in summer, got %v", summer.In(meta.Timezone()))
+	}
+
+	remote := NewMeta()
+	remote.Metadata(meta.Metadata())
+	if summer.In(remote.Timezone()).Hour() != 13 {
+		t.Fatalf("expected fixed offset across metadata, got %v", summer.In(remote.Timezone()))
+	}
+}
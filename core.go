@@ -237,6 +237,18 @@ func (e *coreModule) registerTriggerMethod(methodName, triggerName string, cfg T
 	}
 }
 
+// hasEntry reports whether a local entry of one of kinds exists.
+func (e *coreModule) hasEntry(name string, kinds ...string) bool {
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
+
+	entry, ok := e.entries[name]
+	if !ok || entry.Action == nil {
+		return false
+	}
+	return len(kinds) == 0 || containsString(kinds, entry.kind)
+}
+
 func (e *coreModule) dispatchRetries(name string) []time.Duration {
 	e.mutex.RLock()
 	defer e.mutex.RUnlock()
@@ -258,7 +270,7 @@ func (e *coreModule) Close()     {}
 func (e *coreModule) Wait() {
 	// 待处理，加入自己的退出信号
 	waiter := make(chan os.Signal, 1)
-	signal.Notify(waiter, os.Kill, os.Interrupt, syscall.SIGHUP, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
+	signal.Notify(waiter, os.Kill, os.Interrupt, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
 	<-waiter
 }
 
@@ -302,21 +314,23 @@ func (e *coreModule) Invoke(meta *Meta, name string, value Map, settings ...Map)
 		"module":    "core",
 		"operation": "invoke",
 	}))
+	prevSpan := meta.swapSpan(span)
+	defer meta.swapSpan(prevSpan)
+	begin := time.Now()
 
 	if data, res, ok := e.invokeLocal(meta, name, value, settings...); ok {
-		if res != nil && res.Fail() {
-			span.End(res)
-		} else {
-			span.End()
-		}
+		observeInvocation(entryKind, target, res, time.Since(begin))
+		endSpan(span, res)
 		return data, res
 	}
 	data, res := e.invokeRemote(meta, name, value)
-	if res != nil && res.Fail() {
-		span.End(res)
-	} else {
-		span.End()
+	observed := res
+	if data == nil && (res == nil || res.OK()) && hook.inProcessBus() {
+		// the in-process bus only serves local entries, which invokeLocal just missed.
+		observed = notFound
 	}
+	observeInvocation(coreKindService, target, observed, time.Since(begin))
+	endSpan(span, observed)
 	return data, res
 }
 
@@ -329,15 +343,15 @@ func (e *coreModule) Execute(meta *Meta, name string, value Map, settings ...Map
 		"module":    "core",
 		"operation": "execute",
 	}))
+	prevSpan := meta.swapSpan(span)
+	defer meta.swapSpan(prevSpan)
+	begin := time.Now()
 	data, res, ok := e.invokeLocalWithKinds(meta, name, value, []string{coreKindMethod}, settings...)
 	if !ok {
 		res = Fail.With("method not found: " + name)
 	}
-	if res != nil && res.Fail() {
-		span.End(res)
-	} else {
-		span.End()
-	}
+	observeInvocation(coreKindMethod, name, res, time.Since(begin))
+	endSpan(span, res)
 	return data, res
 }
 
@@ -350,16 +364,22 @@ func (e *coreModule) Request(meta *Meta, name string, value Map, timeout ...time
 		"module":    "core",
 		"operation": "request",
 	}))
+	prevSpan := meta.swapSpan(span)
+	defer meta.swapSpan(prevSpan)
 	waitTimeout := defaultCallTimeout
 	if len(timeout) > 0 && timeout[0] > 0 {
 		waitTimeout = timeout[0]
 	}
-	data, res := hook.Request(meta, name, value, waitTimeout)
-	if res != nil && res.Fail() {
-		span.End(res)
+	begin := time.Now()
+	var data Map
+	var res Res
+	if remain, ok := callTimeout(meta, waitTimeout); ok {
+		data, res = hook.Request(meta, name, value, remain)
 	} else {
-		span.End()
+		res = deadlineResult()
 	}
+	observeInvocation(coreKindService, name, res, time.Since(begin))
+	endSpan(span, res)
 	return data, res
 }
 
@@ -384,6 +404,9 @@ func (e *coreModule) invokeLocalWithKinds(meta *Meta, name string, value Map, ki
 	if meta == nil {
 		meta = NewMeta()
 	}
+	if _, ok := callTimeout(meta, 0); !ok {
+		return nil, deadlineResult(), true
+	}
 	ctx := &Context{
 		Meta:    meta,
 		Name:    name,
@@ -433,7 +456,23 @@ func (e *coreModule) invokeRemote(meta *Meta, name string, value Map) (Map, Res)
 	if meta == nil {
 		meta = NewMeta()
 	}
-	return hook.Request(meta, name, value, defaultCallTimeout)
+	timeout, ok := callTimeout(meta, defaultCallTimeout)
+	if !ok {
+		return nil, deadlineResult()
+	}
+	return hook.Request(meta, name, value, timeout)
+}
+
+// observeInvocation feeds one finished call into stats and the metrics hook.
+func observeInvocation(kind, name string, res Res, elapsed time.Duration) {
+	stats.Record(kind, name, res, elapsed)
+
+	status := OK.Status()
+	if res != nil && res.Fail() {
+		status = statsStatus(res)
+	}
+	hook.Counter("infrago_invocations", map[string]string{"name": name, "kind": kind, "status": status}, 1)
+	hook.Histogram("infrago_invocation_duration_seconds", map[string]string{"name": name, "kind": kind}, elapsed.Seconds())
 }
 
 func cloneMap(in Map) Map {
@@ -0,0 +1,77 @@
+package infra
+
+import (
+	"context"
+	"time"
+
+	. "github.com/infrago/base"
+)
+
+// Deadline returns the absolute deadline carried by meta's context.
+func (m *Meta) Deadline() (time.Time, bool) {
+	return m.Context().Deadline()
+}
+
+// WithDeadline bounds meta's context by deadline, an earlier existing deadline wins.
+// The new context derives from the current one, so contexts already handed out
+// keep running. Every timer is held until Finish.
+func (m *Meta) WithDeadline(deadline time.Time) *Meta {
+	if deadline.IsZero() {
+		return m
+	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	parent := m.ctx
+	if parent == nil {
+		parent = ContextWithMeta(context.Background(), m)
+	}
+	if current, ok := parent.Deadline(); ok && !current.After(deadline) {
+		return m
+	}
+	ctx, cancel := context.WithDeadline(parent, deadline)
+	if previous := m.cancel; previous != nil {
+		m.cancel = func() {
+			cancel()
+			previous()
+		}
+	} else {
+		m.cancel = cancel
+	}
+	m.ctx = ctx
+	return m
+}
+
+// Finish releases the deadlines set by WithDeadline, meta's context is canceled
+// afterwards. Call it once the invocation meta was made for is done.
+func (m *Meta) Finish() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if m.cancel != nil {
+		m.cancel()
+		m.cancel = nil
+	}
+}
+
+// callTimeout shortens timeout to the remaining budget of meta's deadline.
+// It returns false once the deadline has passed.
+func callTimeout(meta *Meta, timeout time.Duration) (time.Duration, bool) {
+	if meta == nil {
+		return timeout, true
+	}
+	deadline, ok := meta.Deadline()
+	if !ok {
+		return timeout, true
+	}
+	remain := time.Until(deadline)
+	if remain <= 0 {
+		return 0, false
+	}
+	if timeout <= 0 || remain < timeout {
+		return remain, true
+	}
+	return timeout, true
+}
+
+func deadlineResult() Res {
+	return errorResult(context.DeadlineExceeded)
+}
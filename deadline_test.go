@@ -0,0 +1,91 @@
+package infra
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/infrago/base"
+)
+
+func TestMetadataCarriesDeadline(t *testing.T) {
+	deadline := time.Now().Add(time.Minute).Truncate(time.Millisecond)
+	ctx, cancel := context.WithDeadline(context.Background(), deadline)
+	defer cancel()
+
+	data := NewMeta().WithContext(ctx).Metadata()
+	if data.Deadline != deadline.UnixMilli() {
+		t.Fatalf("expected deadline %d, got %d", deadline.UnixMilli(), data.Deadline)
+	}
+
+	remote := NewMeta()
+	remote.Metadata(data)
+	got, ok := remote.Context().Deadline()
+	if !ok || !got.Equal(deadline) {
+		t.Fatalf("expected restored deadline %v, got %v %v", deadline, got, ok)
+	}
+
+	timeout, ok := callTimeout(remote, time.Hour)
+	if !ok || timeout > time.Minute {
+		t.Fatalf("expected timeout to shrink to remaining budget, got %v", timeout)
+	}
+}
+
+func TestExpiredDeadlineFailsFast(t *testing.T) {
+	originalCore := core
+	called := false
+	core = &coreModule{
+		entries: map[string]coreEntry{
+			"demo.method": {
+				kind: coreKindMethod,
+				Action: func(*Context) Map {
+					called = true
+					return Map{}
+				},
+			},
+		},
+	}
+	defer func() {
+		core = originalCore
+	}()
+
+	meta := NewMeta().WithDeadline(time.Now().Add(-time.Second))
+	_, res := core.Invoke(meta, "demo.method", nil)
+	if called {
+		t.Fatalf("expected action to be skipped after deadline")
+	}
+	if res == nil || res.OK() || res.Status() != context.DeadlineExceeded.Error() {
+		t.Fatalf("expected deadline result, got %#v", res)
+	}
+}
+
+func TestFinishReleasesDeadline(t *testing.T) {
+	meta := NewMeta().WithDeadline(time.Now().Add(time.Hour))
+	first := meta.Context()
+
+	meta.WithDeadline(time.Now().Add(time.Minute))
+	second := meta.Context()
+	if first.Err() != nil || second.Err() != nil {
+		t.Fatalf("expected handed out contexts live, got %v %v", first.Err(), second.Err())
+	}
+	if deadline, ok := meta.Deadline(); !ok || time.Until(deadline) > time.Minute {
+		t.Fatalf("expected tighter deadline, got %v %v", deadline, ok)
+	}
+
+	// a context derived from meta's and set back must survive a later deadline.
+	derived, stop := context.WithCancel(second)
+	defer stop()
+	meta.WithContext(derived).WithDeadline(time.Now().Add(time.Second * 30))
+	if second.Err() != nil || derived.Err() != nil {
+		t.Fatalf("expected derived context live, got %v %v", second.Err(), derived.Err())
+	}
+
+	ctx := meta.Context()
+	meta.Finish()
+	for i, c := range []context.Context{first, second, ctx} {
+		if c.Err() != context.Canceled {
+			t.Fatalf("expected context %d canceled after finish, got %v", i, c.Err())
+		}
+	}
+	meta.Finish()
+}
@@ -6,7 +6,9 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	base "github.com/infrago/base"
@@ -14,10 +16,23 @@ import (
 	"gopkg.in/yaml.v3"
 )
 
-type defaultBusHook struct{}
+type defaultBusHook struct {
+	mutex   sync.Mutex
+	pending map[string]int
+}
 
-type defaultConfigHook struct{}
-type defaultTraceHook struct{}
+const defaultConfigWatch = 2 * time.Second
+
+// defaultConfigHook loads config files, or a url with --driver=http, and polls
+// them for changes when the watch param is set, e.g. --watch=2s or INFRAGO_WATCH=true.
+type defaultConfigHook struct {
+	mutex   sync.Mutex
+	files   []string
+	sources map[string]string
+	confDir string
+	watch   time.Duration
+	remote  *httpConfigSource
+}
 
 func (h *defaultBusHook) Request(meta *Meta, name string, value base.Map, _ time.Duration) (base.Map, base.Res) {
 	data, res, ok := core.invokeLocalWithKinds(meta, name, value, []string{coreKindService})
@@ -28,18 +43,26 @@ func (h *defaultBusHook) Request(meta *Meta, name string, value base.Map, _ time
 }
 
 func (h *defaultBusHook) Broadcast(meta *Meta, name string, value base.Map) error {
-	_, _, _ = core.invokeLocalWithKinds(meta, name, value, []string{coreKindMessage})
+	h.deliverMessage(meta, name, value)
 	return nil
 }
 
 func (h *defaultBusHook) Rolecast(meta *Meta, name string, value base.Map) error {
-	_, _, _ = core.invokeLocalWithKinds(meta, name, value, []string{coreKindMessage})
+	h.deliverMessage(meta, name, value)
 	return nil
 }
 
 func (h *defaultBusHook) Dispatch(meta *Meta, name string, value base.Map) error {
 	retries := core.dispatchRetries(name)
-	go h.dispatchService(meta, name, value, retries, 1)
+	metadata := Metadata{}
+	if meta != nil {
+		// snapshot now, the producer span has ended by the time the job runs.
+		metadata = meta.Metadata()
+		// queued jobs outlive the producer call, never inherit its deadline.
+		metadata.Deadline = 0
+	}
+	h.trackPending(name, 1)
+	go h.dispatchService(metadata, name, value, retries, 1)
 	return nil
 }
 
@@ -51,36 +74,107 @@ func (h *defaultBusHook) Enqueue(meta *Meta, name string, value base.Map) error
 	return h.Dispatch(meta, name, value)
 }
 
-func (h *defaultBusHook) dispatchService(meta *Meta, name string, value base.Map, retries []time.Duration, attempt int) {
+func (h *defaultBusHook) deliverMessage(meta *Meta, name string, value base.Map) {
+	if !core.hasEntry(name, coreKindMessage) {
+		return
+	}
+	if meta == nil {
+		meta = NewMeta()
+	}
+
+	span := beginConsumerSpan(meta, TraceKindEvent, name, nil)
+	prevSpan := meta.swapSpan(span)
+	begin := time.Now()
+	_, res, _ := core.invokeLocalWithKinds(meta, name, value, []string{coreKindMessage})
+	observeInvocation(TraceKindEvent, name, res, time.Since(begin))
+	meta.swapSpan(prevSpan)
+	endSpan(span, res)
+}
+
+func (h *defaultBusHook) dispatchService(metadata Metadata, name string, value base.Map, retries []time.Duration, attempt int) {
 	if attempt <= 0 {
 		attempt = 1
 	}
+	if !core.hasEntry(name, coreKindService) {
+		h.trackPending(name, -1)
+		return
+	}
 
+	// every attempt starts from the producer snapshot, so retries are sibling spans.
 	localMeta := NewMeta()
-	if meta != nil {
-		localMeta.Metadata(meta.Metadata())
-	}
+	localMeta.Metadata(metadata)
+	defer localMeta.Finish()
 
+	final := dispatchFinal(retries, attempt)
 	setting := base.Map{
 		dispatchAttemptSetting: attempt,
-		dispatchFinalSetting:   dispatchFinal(retries, attempt),
+		dispatchFinalSetting:   final,
 	}
+	span := beginConsumerSpan(localMeta, TraceKindQueue, name, base.Map{
+		"attempt": attempt,
+		"final":   final,
+	})
+	prevSpan := localMeta.swapSpan(span)
+	begin := time.Now()
 	_, res, found := core.invokeLocalWithKinds(localMeta, name, value, []string{coreKindService}, setting)
+	if found {
+		observeInvocation(TraceKindQueue, name, res, time.Since(begin))
+	}
+	localMeta.swapSpan(prevSpan)
+	endSpan(span, res)
+
 	if !found || !dispatchRetryableResult(res) {
+		h.trackPending(name, -1)
 		return
 	}
 
 	delay, ok := dispatchRetryDelay(retries, attempt)
 	if !ok {
+		h.trackPending(name, -1)
 		return
 	}
+	hook.Counter("infrago_dispatch_retries", map[string]string{"name": name}, 1)
 	time.AfterFunc(delay, func() {
-		h.dispatchService(meta, name, value, retries, attempt+1)
+		h.dispatchService(metadata, name, value, retries, attempt+1)
 	})
 }
 
+// beginConsumerSpan opens a consumer span under the producer span carried by meta, and links to it.
+func beginConsumerSpan(meta *Meta, kind, name string, attrs base.Map) TraceSpan {
+	producer := ""
+	if meta.SpanId() != "" {
+		producer = meta.TraceParent()
+	}
+	span := meta.Begin(kind+":"+name, TraceAttrs("infrago", kind, name, base.Map{
+		"module":    "bus",
+		"operation": "consume",
+		"step":      "consumer",
+	}, attrs))
+	if annotator, ok := span.(SpanAnnotator); ok && producer != "" {
+		annotator.Link(producer)
+	}
+	return span
+}
+
+// trackPending counts dispatched jobs that are running or waiting for a retry.
+func (h *defaultBusHook) trackPending(name string, delta int) {
+	h.mutex.Lock()
+	if h.pending == nil {
+		h.pending = make(map[string]int, 0)
+	}
+	h.pending[name] += delta
+	depth := h.pending[name]
+	if depth <= 0 {
+		delete(h.pending, name)
+		depth = 0
+	}
+	h.mutex.Unlock()
+
+	hook.Gauge("infrago_dispatch_pending", map[string]string{"name": name}, float64(depth))
+}
+
 func (h *defaultBusHook) Stats() []ServiceStats {
-	return nil
+	return CoreStats()
 }
 
 func (h *defaultBusHook) ListNodes() []NodeInfo {
@@ -99,10 +193,114 @@ func (h *defaultConfigHook) LoadConfig() (base.Map, error) {
 	if drvName == "" {
 		return nil, nil
 	}
+	if drvName == "http" {
+		return h.loadRemote(params)
+	}
 	if drvName != DEFAULT && drvName != "file" {
 		return nil, errors.New("Unknown config driver: " + drvName)
 	}
-	return loadConfigFromFile(params)
+	cfg, loader, confDir, err := loadConfigFiles(params)
+	if err != nil {
+		return nil, err
+	}
+
+	h.mutex.Lock()
+	h.files, h.sources, h.confDir, h.remote = nil, nil, confDir, nil
+	if loader != nil {
+		h.files, h.sources = loader.files, loader.sources
+	}
+	h.watch = configWatchInterval(params["watch"])
+	h.mutex.Unlock()
+	return cfg, nil
+}
+
+func (h *defaultConfigHook) loadRemote(params base.Map) (base.Map, error) {
+	h.mutex.Lock()
+	remote := h.remote
+	h.mutex.Unlock()
+	if !remote.same(params) {
+		var err error
+		if remote, err = newHTTPConfigSource(params); err != nil {
+			return nil, err
+		}
+	}
+
+	cfg, sources, err := remote.load()
+	if err != nil {
+		return nil, err
+	}
+
+	h.mutex.Lock()
+	h.files, h.sources, h.confDir, h.remote = nil, sources, "", remote
+	h.watch = defaultHTTPConfigWatch
+	if _, ok := params["watch"]; ok {
+		h.watch = configWatchInterval(params["watch"])
+	}
+	h.mutex.Unlock()
+	return cfg, nil
+}
+
+// ConfigSources returns file:line of each key of the last load.
+func (h *defaultConfigHook) ConfigSources() map[string]string {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	out := make(map[string]string, len(h.sources))
+	for key, source := range h.sources {
+		out[key] = source
+	}
+	return out
+}
+
+// WatchConfig polls loaded files and the conf.d directory, it returns at once when watching is off.
+func (h *defaultConfigHook) WatchConfig(done <-chan struct{}, changed func()) {
+	h.mutex.Lock()
+	interval, remote := h.watch, h.remote
+	h.mutex.Unlock()
+	if interval <= 0 {
+		return
+	}
+	if remote != nil {
+		remote.watch(interval, done, changed)
+		return
+	}
+
+	last := h.fingerprint()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+		if next := h.fingerprint(); next != last {
+			last = next
+			changed()
+		}
+	}
+}
+
+// fingerprint summarizes size and mtime of watched files and the conf.d listing.
+func (h *defaultConfigHook) fingerprint() string {
+	h.mutex.Lock()
+	files := append([]string{}, h.files...)
+	confDir := h.confDir
+	h.mutex.Unlock()
+
+	if confDir != "" {
+		if items, err := configDirFiles(confDir); err == nil {
+			files = append(files, items...)
+		}
+	}
+	out := strings.Builder{}
+	for _, file := range files {
+		out.WriteString(file)
+		if info, err := os.Stat(file); err == nil {
+			out.WriteString(":" + strconv.FormatInt(info.Size(), 10) + ":" + strconv.FormatInt(info.ModTime().UnixNano(), 10))
+		}
+		out.WriteByte('\n')
+	}
+	return out.String()
 }
 
 func dispatchRetryableResult(res base.Res) bool {
@@ -147,14 +345,6 @@ func dispatchRetryDelay(retries []time.Duration, attempt int) (time.Duration, bo
 	return delay, true
 }
 
-func (h *defaultTraceHook) Begin(_ *Meta, _ string, _ base.Map) TraceSpan {
-	return noopTraceSpan{}
-}
-
-func (h *defaultTraceHook) Trace(_ *Meta, _ string, _ string, _ base.Map) error {
-	return nil
-}
-
 func parseConfigParams() (string, base.Map, error) {
 	params := base.Map{}
 	for k, v := range parseConfigEnv() {
@@ -191,7 +381,7 @@ func parseConfigEnv() base.Map {
 		}
 		key := parts[0]
 		val := parts[1]
-		if !strings.HasPrefix(key, "INFRAGO_") {
+		if !strings.HasPrefix(key, "INFRAGO_") || strings.HasPrefix(key, configEnvPrefix) {
 			continue
 		}
 		k := strings.ToLower(strings.TrimPrefix(key, "INFRAGO_"))
@@ -204,7 +394,7 @@ func parseConfigArgs() base.Map {
 	args := os.Args[1:]
 	params := base.Map{}
 
-	if len(args) == 1 {
+	if len(args) == 1 && !strings.HasPrefix(args[0], "--") {
 		params["driver"] = DEFAULT
 		params["file"] = args[0]
 		return params
@@ -238,6 +428,26 @@ func parseConfigArgs() base.Map {
 }
 
 func loadConfigFromFile(params base.Map) (base.Map, error) {
+	cfg, _, _, err := loadConfigFiles(params)
+	return cfg, err
+}
+
+// configWatchInterval reads the watch param, true means every 2 seconds.
+func configWatchInterval(value base.Any) time.Duration {
+	raw, _ := value.(string)
+	switch strings.ToLower(strings.TrimSpace(raw)) {
+	case "", "false", "0":
+		return 0
+	case "true":
+		return defaultConfigWatch
+	}
+	if d, err := time.ParseDuration(raw); err == nil && d > 0 {
+		return d
+	}
+	return 0
+}
+
+func loadConfigFiles(params base.Map) (base.Map, *configLoader, string, error) {
 	file := ""
 	if vv, ok := params["file"].(string); ok {
 		file = vv
@@ -252,29 +462,19 @@ func loadConfigFromFile(params base.Map) (base.Map, error) {
 		file = defaultConfigFile()
 	}
 	if file == "" {
-		return nil, nil
+		return nil, nil, "", nil
 	}
 
-	data, err := os.ReadFile(file)
-	if err != nil {
-		return nil, err
-	}
 	format, _ := params["format"].(string)
-	if format == "" {
-		ext := strings.ToLower(filepath.Ext(file))
-		switch ext {
-		case ".json":
-			format = "json"
-		case ".toml", ".tml":
-			format = "toml"
-		case ".yaml", ".yml":
-			format = "yaml"
-		}
+	confDir := filepath.Join(filepath.Dir(file), configDirName)
+	if vv, ok := params["confd"].(string); ok {
+		confDir = vv
 	}
-	if format == "" {
-		format = detectConfigFormat(data)
+	cfg, loader, err := loadConfigTree(file, format, confDir)
+	if err != nil {
+		return nil, nil, "", err
 	}
-	return decodeConfig(data, format)
+	return cfg, loader, confDir, nil
 }
 
 func defaultConfigFile() string {
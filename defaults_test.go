@@ -3,6 +3,8 @@ package infra
 import (
 	"testing"
 	"time"
+
+	. "github.com/infrago/base"
 )
 
 func TestDispatchFinal(t *testing.T) {
@@ -42,3 +44,76 @@ func TestDispatchRetryDelay(t *testing.T) {
 		}
 	}
 }
+
+func TestDispatchTracesProducerAndAttempts(t *testing.T) {
+	traces := &defaultTraceHook{size: 16}
+	done := make(chan struct{})
+	originalCore := core
+	core = &coreModule{
+		entries: map[string]coreEntry{
+			"demo.job": {
+				kind:  coreKindService,
+				retry: []time.Duration{time.Millisecond},
+				Action: func(ctx *Context) Res {
+					if !ctx.Final() {
+						return Fail
+					}
+					return OK
+				},
+			},
+		},
+	}
+	originalHook := hook
+	hook = &infragoHook{}
+	hook.AttachBus(&defaultBusHook{})
+	hook.AttachTrace(traces)
+	hook.AttachMetrics(dispatchTestMetrics{drained: done})
+	defer func() {
+		core = originalCore
+		hook = originalHook
+	}()
+
+	if err := Dispatch("demo.job", Map{}); err != nil {
+		t.Fatalf("dispatch: %v", err)
+	}
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("job did not finish")
+	}
+
+	spans := traces.Spans()
+	if len(spans) != 3 {
+		t.Fatalf("expected producer and 2 attempt spans, got %#v", spans)
+	}
+
+	producer := spans[0]
+	if producer.Attrs["step"] != "producer" || producer.Kind != TraceKindQueue {
+		t.Fatalf("unexpected producer span: %#v", producer)
+	}
+	for i, attempt := range spans[1:] {
+		if attempt.TraceId != producer.TraceId || attempt.ParentSpanId != producer.SpanId {
+			t.Fatalf("attempt %d not under producer: %#v", i+1, attempt)
+		}
+		if len(attempt.Links) != 1 || attempt.Links[0].SpanId != producer.SpanId {
+			t.Fatalf("attempt %d not linked to producer: %#v", i+1, attempt.Links)
+		}
+		if attempt.Attrs["attempt"] != i+1 || attempt.Attrs["final"] != (i == 1) {
+			t.Fatalf("unexpected attempt attrs: %#v", attempt.Attrs)
+		}
+	}
+}
+
+// dispatchTestMetrics signals once the pending gauge drops back to zero.
+type dispatchTestMetrics struct {
+	drained chan struct{}
+}
+
+func (dispatchTestMetrics) Counter(string, map[string]string, float64)   {}
+func (dispatchTestMetrics) Histogram(string, map[string]string, float64) {}
+
+func (m dispatchTestMetrics) Gauge(name string, _ map[string]string, value float64) {
+	if name == "infrago_dispatch_pending" && value == 0 {
+		close(m.drained)
+	}
+}
@@ -0,0 +1,239 @@
+package infra
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	. "github.com/infrago/base"
+)
+
+const (
+	HealthLiveness  = "liveness"
+	HealthReadiness = "readiness"
+
+	defaultHealthTimeout = 3 * time.Second
+)
+
+var (
+	errRuntimeNotReady = errors.New("runtime not started")
+	errRuntimeStopping = errors.New("runtime stopping")
+
+	health = &healthModule{
+		checks: make(map[string]HealthCheck, 0),
+		cache:  make(map[string]healthCached, 0),
+	}
+)
+
+type (
+	// HealthChecker is implemented by modules that can report their own health.
+	// Module checks count as readiness checks.
+	HealthChecker interface {
+		CheckHealth(ctx context.Context) error
+	}
+
+	// HealthCheck registers one named check.
+	// Example:
+	// Register("db", HealthCheck{Kind: HealthReadiness, Timeout: time.Second, Check: ping})
+	HealthCheck struct {
+		Name    string
+		Desc    string
+		Kind    string
+		Timeout time.Duration
+		Cache   time.Duration
+		Check   func(context.Context) error
+	}
+
+	HealthResult struct {
+		Name    string `json:"name"`
+		Kind    string `json:"kind"`
+		Healthy bool   `json:"healthy"`
+		Error   string `json:"error,omitempty"`
+		Latency int64  `json:"latency_ms"`
+		Cached  bool   `json:"cached,omitempty"`
+		Checked int64  `json:"checked"`
+	}
+
+	HealthReport struct {
+		Live   bool           `json:"live"`
+		Ready  bool           `json:"ready"`
+		Checks []HealthResult `json:"checks"`
+	}
+
+	healthModule struct {
+		mutex  sync.Mutex
+		checks map[string]HealthCheck
+		cache  map[string]healthCached
+	}
+
+	healthCached struct {
+		result HealthResult
+		at     time.Time
+	}
+)
+
+func (m *healthModule) Register(name string, value Any) {
+	switch v := value.(type) {
+	case HealthCheck:
+		m.RegisterCheck(name, v)
+	}
+}
+
+func (m *healthModule) RegisterCheck(name string, check HealthCheck) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	name = normalizeToken(name)
+	if name == "" || check.Check == nil {
+		return
+	}
+	if check.Name == "" {
+		check.Name = name
+	}
+	if check.Kind != HealthLiveness {
+		check.Kind = HealthReadiness
+	}
+	if check.Timeout <= 0 {
+		check.Timeout = defaultHealthTimeout
+	}
+	m.checks[name] = check
+	delete(m.cache, name)
+}
+
+func (m *healthModule) Config(Map) {}
+func (m *healthModule) Setup()     {}
+func (m *healthModule) Open()      {}
+func (m *healthModule) Start()     {}
+func (m *healthModule) Stop()      {}
+func (m *healthModule) Close()     {}
+
+// Health runs all registered and module checks and aggregates them.
+func (m *healthModule) Health(ctx context.Context) HealthReport {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	checks := m.collect()
+	results := make([]HealthResult, len(checks))
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func(i int, check HealthCheck) {
+			defer wg.Done()
+			results[i] = m.run(ctx, check)
+		}(i, check)
+	}
+	wg.Wait()
+
+	ready, err := infrago.readiness()
+	runtime := HealthResult{
+		Name:    INFRAGO,
+		Kind:    HealthReadiness,
+		Healthy: ready,
+		Checked: time.Now().Unix(),
+	}
+	if err != nil {
+		runtime.Error = err.Error()
+	}
+	results = append([]HealthResult{runtime}, results...)
+
+	report := HealthReport{Live: true, Ready: true, Checks: results}
+	for _, result := range results {
+		if result.Healthy {
+			continue
+		}
+		report.Ready = false
+		if result.Kind == HealthLiveness {
+			report.Live = false
+		}
+	}
+	return report
+}
+
+func (m *healthModule) collect() []HealthCheck {
+	m.mutex.Lock()
+	checks := make([]HealthCheck, 0, len(m.checks))
+	for _, check := range m.checks {
+		checks = append(checks, check)
+	}
+	m.mutex.Unlock()
+	sort.Slice(checks, func(i, j int) bool { return checks[i].Name < checks[j].Name })
+
+	for _, mod := range infrago.moduleList() {
+		checker, ok := mod.(HealthChecker)
+		if !ok {
+			continue
+		}
+		checks = append(checks, HealthCheck{
+			Name:    fmt.Sprintf("%T", mod),
+			Kind:    HealthReadiness,
+			Timeout: defaultHealthTimeout,
+			Check:   checker.CheckHealth,
+		})
+	}
+	return checks
+}
+
+func (m *healthModule) run(ctx context.Context, check HealthCheck) HealthResult {
+	if check.Cache > 0 {
+		m.mutex.Lock()
+		cached, ok := m.cache[check.Name]
+		m.mutex.Unlock()
+		if ok && time.Since(cached.at) < check.Cache {
+			result := cached.result
+			result.Cached = true
+			return result
+		}
+	}
+
+	begin := time.Now()
+	err := runHealthCheck(ctx, check)
+	result := HealthResult{
+		Name:    check.Name,
+		Kind:    check.Kind,
+		Healthy: err == nil,
+		Latency: time.Since(begin).Milliseconds(),
+		Checked: begin.Unix(),
+	}
+	if err != nil {
+		result.Error = err.Error()
+	}
+
+	if check.Cache > 0 {
+		m.mutex.Lock()
+		m.cache[check.Name] = healthCached{result: result, at: begin}
+		m.mutex.Unlock()
+	}
+	return result
+}
+
+// runHealthCheck enforces the timeout even if the check ignores its context.
+func runHealthCheck(ctx context.Context, check HealthCheck) (err error) {
+	ctx, cancel := context.WithTimeout(ctx, check.Timeout)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- fmt.Errorf("health check panic: %v", r)
+			}
+		}()
+		done <- check.Check(ctx)
+	}()
+
+	select {
+	case err = <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Health aggregates liveness and readiness of the runtime, modules and registered checks.
+func Health() HealthReport {
+	return health.Health(context.Background())
+}
@@ -0,0 +1,72 @@
+package infra
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestHealthAggregatesChecks(t *testing.T) {
+	original := infrago
+	infrago = &infragoRuntime{project: INFRAGO, readyStatus: true}
+	defer func() {
+		infrago = original
+	}()
+
+	calls := 0
+	m := &healthModule{checks: make(map[string]HealthCheck, 0), cache: make(map[string]healthCached, 0)}
+	m.RegisterCheck("db", HealthCheck{
+		Cache: time.Minute,
+		Check: func(context.Context) error {
+			calls++
+			return errors.New("db closed")
+		},
+	})
+	m.RegisterCheck("loop", HealthCheck{
+		Kind:  HealthLiveness,
+		Check: func(context.Context) error { return nil },
+	})
+
+	report := m.Health(context.Background())
+	if !report.Live || report.Ready {
+		t.Fatalf("expected live but not ready, got %#v", report)
+	}
+	if len(report.Checks) != 3 || report.Checks[1].Name != "db" || report.Checks[1].Error != "db closed" {
+		t.Fatalf("unexpected checks: %#v", report.Checks)
+	}
+
+	report = m.Health(context.Background())
+	if calls != 1 || !report.Checks[1].Cached {
+		t.Fatalf("expected cached db result, calls=%d", calls)
+	}
+}
+
+func TestHealthCheckTimeoutAndRuntimeState(t *testing.T) {
+	original := infrago
+	infrago = &infragoRuntime{project: INFRAGO, stopStatus: true}
+	defer func() {
+		infrago = original
+	}()
+
+	m := &healthModule{checks: make(map[string]HealthCheck, 0), cache: make(map[string]healthCached, 0)}
+	m.RegisterCheck("slow", HealthCheck{
+		Kind:    HealthLiveness,
+		Timeout: 10 * time.Millisecond,
+		Check: func(context.Context) error {
+			time.Sleep(time.Second)
+			return nil
+		},
+	})
+
+	report := m.Health(context.Background())
+	if report.Live || report.Ready {
+		t.Fatalf("expected timed out liveness check to fail, got %#v", report)
+	}
+	if report.Checks[0].Error != errRuntimeStopping.Error() {
+		t.Fatalf("expected runtime stopping, got %q", report.Checks[0].Error)
+	}
+	if report.Checks[1].Error != context.DeadlineExceeded.Error() {
+		t.Fatalf("expected deadline error, got %q", report.Checks[1].Error)
+	}
+}
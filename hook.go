@@ -2,6 +2,7 @@ package infra
 
 import (
 	"errors"
+	"log/slog"
 	"sync"
 	"time"
 
@@ -25,10 +26,12 @@ type (
 	infragoHook struct {
 		mutex sync.RWMutex
 
-		bus    BusHook
-		config ConfigHook
-		trace  TraceHook
-		token  TokenHook
+		bus     BusHook
+		config  ConfigHook
+		trace   TraceHook
+		token   TokenHook
+		metrics MetricsHook
+		log     LogHook
 	}
 
 	BusHook interface {
@@ -49,17 +52,49 @@ type (
 		LoadConfig() (base.Map, error)
 	}
 
+	// ConfigWatcher is optionally implemented by config hooks that can detect
+	// changes of their source, changed is called until done is closed.
+	ConfigWatcher interface {
+		WatchConfig(done <-chan struct{}, changed func())
+	}
+
+	// ConfigSourcer is optionally implemented by config hooks that know where
+	// each dotted key of the last load came from, such as "config.toml:12".
+	ConfigSourcer interface {
+		ConfigSources() map[string]string
+	}
+
 	TraceHook interface {
 		Begin(meta *Meta, name string, attrs base.Map) TraceSpan
 		Trace(meta *Meta, name string, status string, attrs base.Map) error
 	}
 
+	// SpanAnnotator is optionally implemented by spans returned from a TraceHook.
+	// Spans without it still work, annotations on them are dropped.
+	SpanAnnotator interface {
+		SetAttr(key string, value base.Any)
+		AddEvent(name string, attrs ...base.Map)
+		RecordError(err base.Any)
+		Link(traceparent string, attrs ...base.Map)
+	}
+
 	TokenHook interface {
 		Sign(req Token) (string, error)
 		Verify(token string) (Token, error)
 		RevokeToken(token string, expires int64) error
 		RevokeTokenID(tokenID string, expires int64) error
 	}
+
+	MetricsHook interface {
+		Counter(name string, labels map[string]string, delta float64)
+		Gauge(name string, labels map[string]string, value float64)
+		Histogram(name string, labels map[string]string, value float64)
+	}
+
+	// LogHook receives runtime and Meta logs, meta is nil for runtime output.
+	LogHook interface {
+		Log(meta *Meta, level slog.Level, msg string, args ...base.Any)
+	}
 )
 
 // Attach dispatches Module.Attach based on type.
@@ -73,6 +108,10 @@ func (h *infragoHook) Attach(value base.Any) {
 		h.AttachTrace(v)
 	case TokenHook:
 		h.AttachToken(v)
+	case MetricsHook:
+		h.AttachMetrics(v)
+	case LogHook:
+		h.AttachLog(v)
 	}
 }
 
@@ -120,6 +159,28 @@ func (h *infragoHook) AttachToken(hook TokenHook) {
 	h.token = hook
 }
 
+func (h *infragoHook) AttachMetrics(hook MetricsHook) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if hook == nil {
+		panic("Invalid metrics hook")
+	}
+
+	h.metrics = hook
+}
+
+func (h *infragoHook) AttachLog(hook LogHook) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if hook == nil {
+		panic("Invalid log hook")
+	}
+
+	h.log = hook
+}
+
 func (h *infragoHook) LoadConfig() (base.Map, error) {
 	h.mutex.RLock()
 	defer h.mutex.RUnlock()
@@ -130,6 +191,14 @@ func (h *infragoHook) LoadConfig() (base.Map, error) {
 	return h.config.LoadConfig()
 }
 
+// inProcessBus reports whether the built-in bus is attached, which has no remote nodes.
+func (h *infragoHook) inProcessBus() bool {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+	_, ok := h.bus.(*defaultBusHook)
+	return ok
+}
+
 // Request sends a bus request (main -> sub).
 func (h *infragoHook) Request(meta *Meta, name string, value base.Map, timeout time.Duration) (base.Map, base.Res) {
 	h.mutex.RLock()
@@ -266,6 +335,65 @@ func (h *infragoHook) RevokeTokenID(tokenID string, expires int64) error {
 	return h.token.RevokeTokenID(tokenID, expires)
 }
 
+func (h *infragoHook) Counter(name string, labels map[string]string, delta float64) {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+	if h.metrics == nil {
+		return
+	}
+	h.metrics.Counter(name, labels, delta)
+}
+
+func (h *infragoHook) Gauge(name string, labels map[string]string, value float64) {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+	if h.metrics == nil {
+		return
+	}
+	h.metrics.Gauge(name, labels, value)
+}
+
+func (h *infragoHook) Histogram(name string, labels map[string]string, value float64) {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+	if h.metrics == nil {
+		return
+	}
+	h.metrics.Histogram(name, labels, value)
+}
+
+func (h *infragoHook) Log(meta *Meta, level slog.Level, msg string, args ...base.Any) {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+	if h.log == nil {
+		return
+	}
+	h.log.Log(meta, level, msg, args...)
+}
+
+func (h *infragoHook) configWatcher() (ConfigWatcher, bool) {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+	watcher, ok := h.config.(ConfigWatcher)
+	return watcher, ok
+}
+
+func (h *infragoHook) configSources() map[string]string {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+	if sourcer, ok := h.config.(ConfigSourcer); ok {
+		return sourcer.ConfigSources()
+	}
+	return map[string]string{}
+}
+
+func (h *infragoHook) metricsWriter() (MetricsWriter, bool) {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+	writer, ok := h.metrics.(MetricsWriter)
+	return writer, ok
+}
+
 type noopTraceSpan struct{}
 
 func (noopTraceSpan) End(...base.Any) {}
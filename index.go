@@ -76,6 +76,48 @@ func Setting() Map {
 	return infrago.Setting()
 }
 
+// SettingString reads a dotted path like "token.codec" from setting, keys are
+// case-insensitive. def is returned when the key is missing or not convertible.
+func SettingString(path string, def string) string {
+	return infrago.SettingString(path, def)
+}
+
+// SettingInt reads an int, numeric text and integral floats are accepted.
+func SettingInt(path string, def int) int {
+	return infrago.SettingInt(path, def)
+}
+
+// SettingDuration reads a duration like "5s", plain numbers count seconds.
+func SettingDuration(path string, def time.Duration) time.Duration {
+	return infrago.SettingDuration(path, def)
+}
+
+// SettingBool reads a bool, "yes"/"no" and "on"/"off" are accepted.
+func SettingBool(path string, def bool) bool {
+	return infrago.SettingBool(path, def)
+}
+
+// SettingSlice reads a list as a copy, a single value becomes a list of one.
+func SettingSlice(path string, def []Any) []Any {
+	return infrago.SettingSlice(path, def)
+}
+
+// DecodeSetting fills the struct out points to from the setting section at path.
+// Fields match by `setting` tag, `json` tag or name, case-insensitively.
+func DecodeSetting(path string, out Any) error {
+	return infrago.DecodeSetting(path, out)
+}
+
+// ConfigSources returns where each key of the loaded config came from.
+func ConfigSources() map[string]string {
+	return infrago.ConfigSources()
+}
+
+// Reload reloads config, swaps setting and notifies modules of the changed keys.
+func Reload() ([]string, error) {
+	return infrago.Reload()
+}
+
 func Identity() infragoIdentity {
 	return infrago.Identity()
 }
@@ -88,6 +130,21 @@ func Role() string {
 	return infrago.Role()
 }
 
+// Stats returns service statistics from the attached bus hook.
+func Stats() []ServiceStats {
+	return hook.Stats()
+}
+
+// ListNodes returns online nodes known by the bus hook.
+func ListNodes() []NodeInfo {
+	return hook.ListNodes()
+}
+
+// ListServices returns online services known by the bus hook.
+func ListServices() []ServiceInfo {
+	return hook.ListServices()
+}
+
 func Arguments(name string, extends ...Vars) Vars {
 	return core.Arguments(name, extends...)
 }
@@ -262,17 +319,17 @@ func invokeTotal(data Map) (int64, bool) {
 
 // Dispatch dispatches one async queued service request.
 func Dispatch(name string, value Map) error {
-	return hook.Dispatch(name, value)
+	return NewMeta().Dispatch(name, value)
 }
 
 // Broadcast dispatches one async event to all subscribers.
 func Broadcast(name string, value Map) error {
-	return hook.Broadcast(name, value)
+	return NewMeta().Broadcast(name, value)
 }
 
 // Rolecast dispatches one async event to one node per role group.
 func Rolecast(name string, value Map) error {
-	return hook.Rolecast(name, value)
+	return NewMeta().Rolecast(name, value)
 }
 
 // Enqueue is compatibility alias of Dispatch.
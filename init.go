@@ -6,9 +6,18 @@ func init() {
 	Mount(codec)
 	Mount(library)
 	Mount(trigger)
+	Mount(health)
+	Mount(admin)
+	Mount(defaultTrace)
+	Mount(defaultLog)
+	Mount(reloader)
+
+	codec.RegisterCodec(SECRET, secretCodec())
 
 	hook.AttachBus(&defaultBusHook{})
 	hook.AttachConfig(&defaultConfigHook{})
-	hook.AttachTrace(&defaultTraceHook{})
+	hook.AttachTrace(defaultTrace)
 	hook.AttachToken(newDefaultTokenHook())
+	hook.AttachMetrics(newDefaultMetricsHook())
+	hook.AttachLog(defaultLog)
 }
@@ -0,0 +1,126 @@
+package infra
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+
+	. "github.com/infrago/base"
+)
+
+var defaultLog = &SlogHook{}
+
+// SlogHook is the default LogHook, writing through a slog.Logger.
+// Without a logger it uses slog.Default() at call time, so slog.SetDefault
+// after startup still takes effect, unless [infrago.log] sets level or format.
+type SlogHook struct {
+	mutex  sync.RWMutex
+	logger *slog.Logger
+}
+
+// NewSlogHook creates a LogHook backed by logger, mount it to replace the default:
+// infra.Mount(infra.NewSlogHook(slog.New(slog.NewJSONHandler(os.Stdout, nil))))
+func NewSlogHook(logger *slog.Logger) *SlogHook {
+	return &SlogHook{logger: logger}
+}
+
+func (h *SlogHook) Register(string, Any) {}
+
+// Config reads [infrago.log] level and format (text or json).
+func (h *SlogHook) Config(global Map) {
+	runtimeCfg, ok := global["infrago"].(Map)
+	if !ok {
+		return
+	}
+	cfg, ok := runtimeCfg["log"].(Map)
+	if !ok {
+		return
+	}
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if h.logger != nil {
+		return
+	}
+
+	level := slog.LevelInfo
+	if vv, ok := cfg["level"].(string); ok {
+		_ = level.UnmarshalText([]byte(vv))
+	}
+	options := &slog.HandlerOptions{Level: level}
+	if vv, _ := cfg["format"].(string); strings.EqualFold(vv, "json") {
+		h.logger = slog.New(slog.NewJSONHandler(os.Stderr, options))
+	} else {
+		h.logger = slog.New(slog.NewTextHandler(os.Stderr, options))
+	}
+}
+
+func (h *SlogHook) Setup() {}
+func (h *SlogHook) Open()  {}
+func (h *SlogHook) Start() {}
+func (h *SlogHook) Stop()  {}
+func (h *SlogHook) Close() {}
+
+func (h *SlogHook) Log(meta *Meta, level slog.Level, msg string, args ...Any) {
+	h.mutex.RLock()
+	logger := h.logger
+	h.mutex.RUnlock()
+	if logger == nil {
+		logger = slog.Default()
+	}
+	ctx := context.Background()
+	if meta != nil {
+		ctx = meta.Context()
+	}
+	logger.Log(ctx, level, msg, args...)
+}
+
+// Debug logs with trace id, span id, entry, node and role of meta.
+func (m *Meta) Debug(msg string, args ...Any) {
+	m.log(slog.LevelDebug, msg, args...)
+}
+
+// Info logs with trace id, span id, entry, node and role of meta.
+func (m *Meta) Info(msg string, args ...Any) {
+	m.log(slog.LevelInfo, msg, args...)
+}
+
+// Warn logs with trace id, span id, entry, node and role of meta.
+func (m *Meta) Warn(msg string, args ...Any) {
+	m.log(slog.LevelWarn, msg, args...)
+}
+
+// Error logs with trace id, span id, entry, node and role of meta.
+func (m *Meta) Error(msg string, args ...Any) {
+	m.log(slog.LevelError, msg, args...)
+}
+
+func (m *Meta) log(level slog.Level, msg string, args ...Any) {
+	hook.Log(m, level, msg, append(metaLogArgs(m), args...)...)
+}
+
+// metaLogArgs returns the correlation fields of meta as slog key/value pairs.
+func metaLogArgs(meta *Meta) []Any {
+	identity := Identity()
+	args := make([]Any, 0, 10)
+	if meta != nil {
+		if v := meta.TraceId(); v != "" {
+			args = append(args, "trace_id", v)
+		}
+		if v := meta.SpanId(); v != "" {
+			args = append(args, "span_id", v)
+		}
+		if v := meta.TraceEntry(); v != "" {
+			args = append(args, "entry", v)
+		}
+	}
+	if identity.Node != "" {
+		args = append(args, "node", identity.Node)
+	}
+	if identity.Role != "" {
+		args = append(args, "role", identity.Role)
+	}
+	return args
+}
@@ -0,0 +1,49 @@
+package infra
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	. "github.com/infrago/base"
+)
+
+func TestMetaLogIncludesTraceFields(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	originalHook := hook
+	hook = &infragoHook{}
+	hook.AttachLog(NewSlogHook(slog.New(slog.NewJSONHandler(buffer, &slog.HandlerOptions{Level: slog.LevelDebug}))))
+	defer func() {
+		hook = originalHook
+	}()
+
+	meta := NewMeta()
+	meta.TraceId("4bf92f3577b34da6a3ce929d0e0e4736")
+	meta.SpanId("00f067aa0ba902b7")
+	meta.TraceEntry("demo.echo")
+	meta.Warn("cache miss", "key", "user:1")
+
+	record := map[string]any{}
+	if err := json.Unmarshal(buffer.Bytes(), &record); err != nil {
+		t.Fatalf("decode log: %v (%s)", err, buffer.String())
+	}
+	if record["level"] != "WARN" || record["msg"] != "cache miss" || record["key"] != "user:1" {
+		t.Fatalf("unexpected record: %v", record)
+	}
+	if record["trace_id"] != meta.TraceId() || record["span_id"] != meta.SpanId() || record["entry"] != "demo.echo" {
+		t.Fatalf("expected trace fields: %v", record)
+	}
+	if record["role"] != Identity().Role {
+		t.Fatalf("expected role field: %v", record)
+	}
+}
+
+func TestSlogHookConfigLevel(t *testing.T) {
+	h := &SlogHook{}
+	h.Config(Map{"infrago": Map{"log": Map{"level": "warn"}}})
+	if h.logger == nil || h.logger.Enabled(context.Background(), slog.LevelInfo) || !h.logger.Enabled(context.Background(), slog.LevelWarn) {
+		t.Fatalf("expected warn level logger")
+	}
+}
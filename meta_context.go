@@ -0,0 +1,82 @@
+package infra
+
+import (
+	"context"
+	"log/slog"
+)
+
+type metaContextKey struct{}
+
+// logHandler adds trace fields of the Meta carried by the log context.
+type logHandler struct {
+	next slog.Handler
+}
+
+// ContextWithMeta returns a copy of ctx carrying meta.
+func ContextWithMeta(ctx context.Context, meta *Meta) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return context.WithValue(ctx, metaContextKey{}, meta)
+}
+
+// MetaFromContext returns the Meta carried by ctx, or nil when there is none.
+// Contexts from Meta.Context() always carry their Meta, so libraries that only
+// receive a context.Context can get back the token, language and trace ids.
+func MetaFromContext(ctx context.Context) *Meta {
+	if ctx == nil {
+		return nil
+	}
+	meta, _ := ctx.Value(metaContextKey{}).(*Meta)
+	return meta
+}
+
+// NewLogHandler wraps next so records logged with a Meta context get
+// trace_id, span_id and entry fields, nil wraps the default slog handler.
+// Example:
+// slog.SetDefault(slog.New(infra.NewLogHandler(slog.NewJSONHandler(os.Stdout, nil))))
+func NewLogHandler(next slog.Handler) slog.Handler {
+	if next == nil {
+		next = slog.Default().Handler()
+	}
+	return &logHandler{next: next}
+}
+
+func (h *logHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *logHandler) Handle(ctx context.Context, record slog.Record) error {
+	meta := MetaFromContext(ctx)
+	if meta == nil || meta.TraceId() == "" || logRecordHas(record, "trace_id") {
+		return h.next.Handle(ctx, record)
+	}
+
+	record = record.Clone()
+	record.AddAttrs(slog.String("trace_id", meta.TraceId()))
+	if v := meta.SpanId(); v != "" {
+		record.AddAttrs(slog.String("span_id", v))
+	}
+	if v := meta.TraceEntry(); v != "" {
+		record.AddAttrs(slog.String("entry", v))
+	}
+	return h.next.Handle(ctx, record)
+}
+
+func (h *logHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &logHandler{next: h.next.WithAttrs(attrs)}
+}
+
+func (h *logHandler) WithGroup(name string) slog.Handler {
+	return &logHandler{next: h.next.WithGroup(name)}
+}
+
+// logRecordHas skips records already correlated by Meta logging.
+func logRecordHas(record slog.Record, key string) bool {
+	found := false
+	record.Attrs(func(attr slog.Attr) bool {
+		found = attr.Key == key
+		return !found
+	})
+	return found
+}
@@ -0,0 +1,60 @@
+package infra
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestMetaContextCarriesMeta(t *testing.T) {
+	meta := NewMeta()
+	if MetaFromContext(meta.Context()) != meta {
+		t.Fatalf("expected NewMeta context to carry meta")
+	}
+
+	type key struct{}
+	parent := context.WithValue(context.Background(), key{}, "v")
+	meta.WithContext(parent)
+	if MetaFromContext(meta.Context()) != meta || meta.Context().Value(key{}) != "v" {
+		t.Fatalf("expected WithContext to keep parent values and carry meta")
+	}
+
+	meta.WithDeadline(time.Now().Add(time.Minute))
+	if MetaFromContext(meta.Context()) != meta {
+		t.Fatalf("expected deadline context to carry meta")
+	}
+	if MetaFromContext(context.Background()) != nil {
+		t.Fatalf("expected no meta in background context")
+	}
+}
+
+func TestLogHandlerAddsTraceFields(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	logger := slog.New(NewLogHandler(slog.NewJSONHandler(buffer, nil)))
+
+	meta := NewMeta()
+	meta.TraceId("4bf92f3577b34da6a3ce929d0e0e4736")
+	meta.SpanId("00f067aa0ba902b7")
+	logger.InfoContext(meta.Context(), "query", "table", "users")
+
+	record := map[string]any{}
+	if err := json.Unmarshal(buffer.Bytes(), &record); err != nil {
+		t.Fatalf("decode log: %v", err)
+	}
+	if record["trace_id"] != meta.TraceId() || record["span_id"] != meta.SpanId() || record["table"] != "users" {
+		t.Fatalf("unexpected record: %v", record)
+	}
+
+	buffer.Reset()
+	logger.InfoContext(context.Background(), "plain")
+	record = map[string]any{}
+	if err := json.Unmarshal(buffer.Bytes(), &record); err != nil {
+		t.Fatalf("decode log: %v", err)
+	}
+	if _, ok := record["trace_id"]; ok {
+		t.Fatalf("expected no trace fields without meta: %v", record)
+	}
+}
@@ -0,0 +1,251 @@
+package infra
+
+import (
+	"bufio"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const metricsContentType = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+
+const (
+	metricsKindCounter   = "counter"
+	metricsKindGauge     = "gauge"
+	metricsKindHistogram = "histogram"
+)
+
+// MetricsBuckets are the default histogram upper bounds, in seconds.
+var MetricsBuckets = []float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+type (
+	// MetricsWriter is implemented by metrics hooks that can render their samples,
+	// MetricsHandler serves whatever the attached hook writes.
+	MetricsWriter interface {
+		WriteMetrics(w io.Writer) error
+	}
+
+	defaultMetricsHook struct {
+		mutex    sync.Mutex
+		families map[string]*metricsFamily
+	}
+
+	metricsFamily struct {
+		kind   string
+		series map[string]*metricsSeries
+	}
+
+	metricsSeries struct {
+		labels  [][2]string
+		value   float64
+		buckets []uint64
+		count   uint64
+		sum     float64
+	}
+)
+
+func newDefaultMetricsHook() *defaultMetricsHook {
+	return &defaultMetricsHook{families: make(map[string]*metricsFamily, 0)}
+}
+
+// Counter adds delta to one counter series, negative deltas are ignored.
+func (h *defaultMetricsHook) Counter(name string, labels map[string]string, delta float64) {
+	if delta < 0 {
+		return
+	}
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if series := h.series(metricsKindCounter, strings.TrimSuffix(name, "_total"), labels); series != nil {
+		series.value += delta
+	}
+}
+
+// Gauge sets one gauge series to value.
+func (h *defaultMetricsHook) Gauge(name string, labels map[string]string, value float64) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if series := h.series(metricsKindGauge, name, labels); series != nil {
+		series.value = value
+	}
+}
+
+// Histogram observes value into one histogram series using MetricsBuckets.
+func (h *defaultMetricsHook) Histogram(name string, labels map[string]string, value float64) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	series := h.series(metricsKindHistogram, name, labels)
+	if series == nil {
+		return
+	}
+	if series.buckets == nil {
+		series.buckets = make([]uint64, len(MetricsBuckets))
+	}
+	for i, bound := range MetricsBuckets {
+		if value <= bound {
+			series.buckets[i]++
+		}
+	}
+	series.count++
+	series.sum += value
+}
+
+func (h *defaultMetricsHook) series(kind, name string, labels map[string]string) *metricsSeries {
+	name = metricsName(name)
+	if name == "" {
+		return nil
+	}
+	family, ok := h.families[name]
+	if !ok {
+		family = &metricsFamily{kind: kind, series: make(map[string]*metricsSeries, 0)}
+		h.families[name] = family
+	}
+	if family.kind != kind {
+		// one name can only have one type, the first registration wins.
+		return nil
+	}
+
+	pairs := make([][2]string, 0, len(labels))
+	for k, v := range labels {
+		if k = metricsLabelName(k); k != "" {
+			pairs = append(pairs, [2]string{k, v})
+		}
+	}
+	sort.Slice(pairs, func(i, j int) bool { return pairs[i][0] < pairs[j][0] })
+	key := metricsLabelText(pairs)
+
+	series, ok := family.series[key]
+	if !ok {
+		series = &metricsSeries{labels: pairs}
+		family.series[key] = series
+	}
+	return series
+}
+
+// WriteMetrics renders all series in OpenMetrics text format.
+func (h *defaultMetricsHook) WriteMetrics(w io.Writer) error {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	out := bufio.NewWriter(w)
+	names := make([]string, 0, len(h.families))
+	for name := range h.families {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		family := h.families[name]
+		out.WriteString("# TYPE " + name + " " + family.kind + "\n")
+
+		keys := make([]string, 0, len(family.series))
+		for key := range family.series {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			series := family.series[key]
+			switch family.kind {
+			case metricsKindCounter:
+				out.WriteString(name + "_total" + key + " " + metricsValue(series.value) + "\n")
+			case metricsKindGauge:
+				out.WriteString(name + key + " " + metricsValue(series.value) + "\n")
+			case metricsKindHistogram:
+				for i, bound := range MetricsBuckets {
+					labels := metricsLabelText(withMetricsLabel(series.labels, "le", metricsValue(bound)))
+					out.WriteString(name + "_bucket" + labels + " " + strconv.FormatUint(series.buckets[i], 10) + "\n")
+				}
+				labels := metricsLabelText(withMetricsLabel(series.labels, "le", "+Inf"))
+				out.WriteString(name + "_bucket" + labels + " " + strconv.FormatUint(series.count, 10) + "\n")
+				out.WriteString(name + "_sum" + key + " " + metricsValue(series.sum) + "\n")
+				out.WriteString(name + "_count" + key + " " + strconv.FormatUint(series.count, 10) + "\n")
+			}
+		}
+	}
+	out.WriteString("# EOF\n")
+	return out.Flush()
+}
+
+// MetricsHandler serves the attached metrics hook in OpenMetrics text format.
+func MetricsHandler() http.Handler {
+	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		writer, ok := hook.metricsWriter()
+		if !ok {
+			http.Error(res, "metrics hook does not render samples", http.StatusNotFound)
+			return
+		}
+		res.Header().Set("Content-Type", metricsContentType)
+		if err := writer.WriteMetrics(res); err != nil {
+			http.Error(res, err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
+
+// metricsName keeps name to [a-zA-Z_:][a-zA-Z0-9_:]*, as metric names allow.
+func metricsName(name string) string {
+	return metricsSanitize(name, true)
+}
+
+// metricsLabelName keeps name to [a-zA-Z_][a-zA-Z0-9_]*, label names have no ':'.
+func metricsLabelName(name string) string {
+	return metricsSanitize(name, false)
+}
+
+func metricsSanitize(name string, colon bool) string {
+	name = strings.TrimSpace(name)
+	buf := make([]byte, 0, len(name))
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c == '_', colon && c == ':':
+			buf = append(buf, c)
+		case c >= '0' && c <= '9':
+			if len(buf) == 0 {
+				buf = append(buf, '_')
+			}
+			buf = append(buf, c)
+		default:
+			buf = append(buf, '_')
+		}
+	}
+	return string(buf)
+}
+
+func metricsLabelText(pairs [][2]string) string {
+	if len(pairs) == 0 {
+		return ""
+	}
+	parts := make([]string, 0, len(pairs))
+	for _, pair := range pairs {
+		parts = append(parts, pair[0]+`="`+metricsEscape(pair[1])+`"`)
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+func withMetricsLabel(pairs [][2]string, key, value string) [][2]string {
+	out := make([][2]string, 0, len(pairs)+1)
+	out = append(out, pairs...)
+	return append(out, [2]string{key, value})
+}
+
+func metricsEscape(v string) string {
+	v = strings.ReplaceAll(v, `\`, `\\`)
+	v = strings.ReplaceAll(v, "\n", `\n`)
+	return strings.ReplaceAll(v, `"`, `\"`)
+}
+
+func metricsValue(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
@@ -0,0 +1,71 @@
+package infra
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDefaultMetricsHookRendersOpenMetrics(t *testing.T) {
+	metrics := newDefaultMetricsHook()
+	metrics.Counter("infrago_invocations_total", map[string]string{"name": "demo.get", "status": "ok"}, 1)
+	metrics.Counter("infrago_invocations", map[string]string{"status": "ok", "name": "demo.get"}, 2)
+	metrics.Gauge("infrago_dispatch_pending", map[string]string{"name": `a"b`}, 3)
+	metrics.Histogram("infrago_invocation_duration_seconds", nil, 0.02)
+	metrics.Gauge("infrago_invocations", nil, 9)
+	metrics.Counter("app:requests", map[string]string{"ns:key": "v"}, 1)
+
+	out := &strings.Builder{}
+	if err := metrics.WriteMetrics(out); err != nil {
+		t.Fatalf("write metrics: %v", err)
+	}
+	text := out.String()
+
+	expects := []string{
+		"# TYPE infrago_invocations counter\n",
+		`infrago_invocations_total{name="demo.get",status="ok"} 3` + "\n",
+		`infrago_dispatch_pending{name="a\"b"} 3` + "\n",
+		`infrago_invocation_duration_seconds_bucket{le="0.01"} 0` + "\n",
+		`infrago_invocation_duration_seconds_bucket{le="0.025"} 1` + "\n",
+		`infrago_invocation_duration_seconds_bucket{le="+Inf"} 1` + "\n",
+		"infrago_invocation_duration_seconds_count 1\n",
+		`app:requests_total{ns_key="v"} 1` + "\n",
+	}
+	for _, expect := range expects {
+		if !strings.Contains(text, expect) {
+			t.Fatalf("expected %q in output:\n%s", expect, text)
+		}
+	}
+	if !strings.HasSuffix(text, "# EOF\n") {
+		t.Fatalf("expected EOF marker, got:\n%s", text)
+	}
+	if strings.Contains(text, "infrago_invocations 9") {
+		t.Fatalf("expected conflicting gauge to be dropped")
+	}
+}
+
+func TestMetricsHandlerServesAttachedHook(t *testing.T) {
+	original := hook
+	hook = &infragoHook{}
+	defer func() {
+		hook = original
+	}()
+
+	recorder := httptest.NewRecorder()
+	MetricsHandler().ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	if recorder.Code != 404 {
+		t.Fatalf("expected 404 without metrics hook, got %d", recorder.Code)
+	}
+
+	hook.AttachMetrics(newDefaultMetricsHook())
+	observeInvocation(coreKindMethod, "demo.get", Invalid, 0)
+
+	recorder = httptest.NewRecorder()
+	MetricsHandler().ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	if ct := recorder.Header().Get("Content-Type"); !strings.HasPrefix(ct, "application/openmetrics-text") {
+		t.Fatalf("unexpected content type %q", ct)
+	}
+	if !strings.Contains(recorder.Body.String(), `infrago_invocations_total{kind="method",name="demo.get",status="invalid"} 1`) {
+		t.Fatalf("expected invocation counter, got:\n%s", recorder.Body.String())
+	}
+}
@@ -0,0 +1,447 @@
+package infra
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	. "github.com/infrago/base"
+)
+
+const (
+	defaultOTLPEndpoint  = "http://localhost:4318/v1/traces"
+	defaultOTLPBatchSize = 512
+	defaultOTLPInterval  = 5 * time.Second
+	defaultOTLPTimeout   = 10 * time.Second
+)
+
+// OTLP span kinds and status codes.
+const (
+	otlpKindInternal = 1
+	otlpKindServer   = 2
+	otlpKindClient   = 3
+	otlpKindProducer = 4
+	otlpKindConsumer = 5
+
+	otlpStatusOK    = 1
+	otlpStatusError = 2
+)
+
+type (
+	// OTLPConfig configures the OTLP/JSON trace exporter.
+	// File takes precedence over Endpoint when both are set.
+	OTLPConfig struct {
+		Endpoint  string
+		File      string
+		Headers   map[string]string
+		BatchSize int
+		Interval  time.Duration
+		Timeout   time.Duration
+		Resource  Map
+	}
+
+	// OTLPTraceHook is a TraceHook exporting spans as OTLP/JSON.
+	// Mount it to replace the default trace hook:
+	// infra.Mount(infra.NewOTLPTraceHook(infra.OTLPConfig{Endpoint: "http://collector:4318/v1/traces"}))
+	OTLPTraceHook struct {
+		mutex  sync.Mutex
+		config OTLPConfig
+		client *http.Client
+		spans  []SpanRecord
+		flush  chan struct{}
+		done   chan struct{}
+		wg     sync.WaitGroup
+
+		// exportMutex keeps batches in order across timer and Stop flushes.
+		exportMutex sync.Mutex
+	}
+)
+
+// NewOTLPTraceHook creates an OTLP trace exporter, [infrago.otlp] can override its config.
+func NewOTLPTraceHook(configs ...OTLPConfig) *OTLPTraceHook {
+	config := OTLPConfig{}
+	if len(configs) > 0 {
+		config = configs[0]
+	}
+	h := &OTLPTraceHook{config: config}
+	h.normalize()
+	return h
+}
+
+func (h *OTLPTraceHook) normalize() {
+	if h.config.Endpoint == "" {
+		h.config.Endpoint = defaultOTLPEndpoint
+	}
+	if h.config.BatchSize <= 0 {
+		h.config.BatchSize = defaultOTLPBatchSize
+	}
+	if h.config.Interval <= 0 {
+		h.config.Interval = defaultOTLPInterval
+	}
+	if h.config.Timeout <= 0 {
+		h.config.Timeout = defaultOTLPTimeout
+	}
+	h.client = &http.Client{Timeout: h.config.Timeout}
+}
+
+func (h *OTLPTraceHook) Register(string, Any) {}
+
+// Config reads [infrago.otlp].
+func (h *OTLPTraceHook) Config(global Map) {
+	runtimeCfg, ok := global["infrago"].(Map)
+	if !ok {
+		return
+	}
+	cfg, ok := runtimeCfg["otlp"].(Map)
+	if !ok {
+		return
+	}
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if vv, ok := cfg["endpoint"].(string); ok && vv != "" {
+		h.config.Endpoint = vv
+	}
+	if vv, ok := cfg["file"].(string); ok {
+		h.config.File = vv
+	}
+	if vv := settingInt(cfg["batch"]); vv > 0 {
+		h.config.BatchSize = vv
+	}
+	if vv, ok := configDuration(cfg["interval"]); ok {
+		h.config.Interval = vv
+	}
+	if vv, ok := configDuration(cfg["timeout"]); ok {
+		h.config.Timeout = vv
+	}
+	if vv, ok := cfg["headers"].(Map); ok {
+		h.config.Headers = make(map[string]string, len(vv))
+		for k, v := range vv {
+			h.config.Headers[k] = fmt.Sprintf("%v", v)
+		}
+	}
+	if vv, ok := cfg["resource"].(Map); ok {
+		h.config.Resource = vv
+	}
+	h.normalize()
+}
+
+func (h *OTLPTraceHook) Setup() {}
+func (h *OTLPTraceHook) Open()  {}
+
+// Start launches the periodic flush loop.
+func (h *OTLPTraceHook) Start() {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if h.done != nil {
+		return
+	}
+	h.flush = make(chan struct{}, 1)
+	h.done = make(chan struct{})
+	h.wg.Add(1)
+	go h.loop(h.config.Interval, h.flush, h.done)
+}
+
+// Stop ends the flush loop and exports everything still buffered.
+func (h *OTLPTraceHook) Stop() {
+	h.mutex.Lock()
+	done := h.done
+	h.done = nil
+	// spans ended after Stop, from shutdown hooks say, are exported inline.
+	h.flush = nil
+	h.mutex.Unlock()
+
+	if done != nil {
+		close(done)
+		h.wg.Wait()
+	}
+	_ = h.Flush()
+}
+
+// Close exports everything still buffered.
+func (h *OTLPTraceHook) Close() {
+	_ = h.Flush()
+}
+
+func (h *OTLPTraceHook) loop(interval time.Duration, flush, done chan struct{}) {
+	defer h.wg.Done()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		case <-flush:
+		}
+		_ = h.Flush()
+	}
+}
+
+func (h *OTLPTraceHook) Begin(meta *Meta, name string, attrs Map) TraceSpan {
+	if meta == nil {
+		return noopTraceSpan{}
+	}
+	return beginMetaSpan(meta, name, attrs, h.enqueue)
+}
+
+// Trace exports one instant span under the current span.
+func (h *OTLPTraceHook) Trace(meta *Meta, name string, status string, attrs Map) error {
+	if meta == nil {
+		return errors.New("trace requires meta")
+	}
+	span := beginMetaSpan(meta, name, attrs, h.enqueue)
+	if status != "" {
+		span.End(textResult(status))
+	} else {
+		span.End()
+	}
+	return nil
+}
+
+func (h *OTLPTraceHook) enqueue(record SpanRecord) {
+	h.mutex.Lock()
+	h.spans = append(h.spans, record)
+	full := len(h.spans) >= h.config.BatchSize
+	flush := h.flush
+	h.mutex.Unlock()
+
+	if !full {
+		return
+	}
+	if flush == nil {
+		// no flush loop running, export inline.
+		_ = h.Flush()
+		return
+	}
+	select {
+	case flush <- struct{}{}:
+	default:
+	}
+}
+
+// Flush synchronously exports all buffered spans in batches.
+func (h *OTLPTraceHook) Flush() error {
+	h.exportMutex.Lock()
+	defer h.exportMutex.Unlock()
+
+	var errs []error
+	for {
+		h.mutex.Lock()
+		size := min(len(h.spans), h.config.BatchSize)
+		batch := h.spans[:size:size]
+		h.spans = h.spans[size:]
+		config := h.config
+		client := h.client
+		h.mutex.Unlock()
+
+		if len(batch) == 0 {
+			break
+		}
+		if err := exportOTLP(client, config, batch); err != nil {
+			hook.Counter("infrago_trace_export_failures", map[string]string{"exporter": "otlp"}, 1)
+			hook.Log(nil, slog.LevelWarn, "otlp export failed", "spans", len(batch), "error", err)
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func exportOTLP(client *http.Client, config OTLPConfig, spans []SpanRecord) error {
+	body, err := json.Marshal(otlpPayload(config.Resource, spans))
+	if err != nil {
+		return err
+	}
+
+	if config.File != "" {
+		file, err := os.OpenFile(config.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		_, err = file.Write(append(body, '\n'))
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), config.Timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, config.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range config.Headers {
+		req.Header.Set(k, v)
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode >= 300 {
+		return fmt.Errorf("otlp export failed: %s", res.Status)
+	}
+	return nil
+}
+
+// otlpPayload converts spans into an OTLP/JSON ExportTraceServiceRequest.
+func otlpPayload(resource Map, spans []SpanRecord) Map {
+	identity := Identity()
+	attrs := Map{
+		"service.name":           identity.Project,
+		"service.instance.id":    identity.Node,
+		"deployment.environment": identity.Profile,
+		"infrago.role":           identity.Role,
+	}
+	for k, v := range resource {
+		attrs[k] = v
+	}
+
+	items := make([]Map, 0, len(spans))
+	for _, span := range spans {
+		items = append(items, otlpSpan(span))
+	}
+
+	return Map{
+		"resourceSpans": []Map{{
+			"resource": Map{"attributes": otlpAttributes(attrs)},
+			"scopeSpans": []Map{{
+				"scope": Map{"name": INFRAGO},
+				"spans": items,
+			}},
+		}},
+	}
+}
+
+func otlpSpan(span SpanRecord) Map {
+	name := span.Name
+	if span.Kind != "" && span.Entry != "" {
+		name = span.Kind + ":" + span.Entry
+	}
+
+	attrs := Map{}
+	for k, v := range span.Attrs {
+		attrs[k] = v
+	}
+	attrs["infrago.code"] = span.Code
+	if span.Status != "" {
+		attrs["infrago.status"] = span.Status
+	}
+
+	status := Map{"code": otlpStatusOK}
+	if span.Error != "" {
+		status = Map{"code": otlpStatusError, "message": span.Error}
+	}
+
+	out := Map{
+		"traceId":           span.TraceId,
+		"spanId":            span.SpanId,
+		"name":              name,
+		"kind":              otlpSpanKind(span.Attrs),
+		"startTimeUnixNano": strconv.FormatInt(span.Start.UnixNano(), 10),
+		"endTimeUnixNano":   strconv.FormatInt(span.End.UnixNano(), 10),
+		"attributes":        otlpAttributes(attrs),
+		"status":            status,
+	}
+	if span.ParentSpanId != "" {
+		out["parentSpanId"] = span.ParentSpanId
+	}
+	if len(span.Events) > 0 {
+		events := make([]Map, 0, len(span.Events))
+		for _, event := range span.Events {
+			events = append(events, Map{
+				"name":         event.Name,
+				"timeUnixNano": strconv.FormatInt(event.Time.UnixNano(), 10),
+				"attributes":   otlpAttributes(event.Attrs),
+			})
+		}
+		out["events"] = events
+	}
+	if len(span.Links) > 0 {
+		links := make([]Map, 0, len(span.Links))
+		for _, link := range span.Links {
+			links = append(links, Map{
+				"traceId":    link.TraceId,
+				"spanId":     link.SpanId,
+				"attributes": otlpAttributes(link.Attrs),
+			})
+		}
+		out["links"] = links
+	}
+	return out
+}
+
+func otlpSpanKind(attrs Map) int {
+	step, _ := attrs["step"].(string)
+	switch step {
+	case "server":
+		return otlpKindServer
+	case "client":
+		return otlpKindClient
+	case "producer":
+		return otlpKindProducer
+	case "consumer":
+		return otlpKindConsumer
+	default:
+		return otlpKindInternal
+	}
+}
+
+func otlpAttributes(attrs Map) []Map {
+	keys := make([]string, 0, len(attrs))
+	for k := range attrs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	out := make([]Map, 0, len(keys))
+	for _, k := range keys {
+		out = append(out, Map{"key": k, "value": otlpValue(attrs[k])})
+	}
+	return out
+}
+
+func otlpValue(value Any) Map {
+	switch v := value.(type) {
+	case string:
+		return Map{"stringValue": v}
+	case bool:
+		return Map{"boolValue": v}
+	case int:
+		return Map{"intValue": strconv.FormatInt(int64(v), 10)}
+	case int32:
+		return Map{"intValue": strconv.FormatInt(int64(v), 10)}
+	case int64:
+		return Map{"intValue": strconv.FormatInt(v, 10)}
+	case uint:
+		return Map{"intValue": strconv.FormatUint(uint64(v), 10)}
+	case uint64:
+		return Map{"intValue": strconv.FormatUint(v, 10)}
+	case float32:
+		return Map{"doubleValue": float64(v)}
+	case float64:
+		return Map{"doubleValue": v}
+	case time.Duration:
+		return Map{"intValue": strconv.FormatInt(v.Milliseconds(), 10)}
+	case []string:
+		values := make([]Map, 0, len(v))
+		for _, item := range v {
+			values = append(values, Map{"stringValue": item})
+		}
+		return Map{"arrayValue": Map{"values": values}}
+	case nil:
+		return Map{"stringValue": ""}
+	default:
+		return Map{"stringValue": fmt.Sprintf("%v", v)}
+	}
+}
@@ -0,0 +1,122 @@
+package infra
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	. "github.com/infrago/base"
+)
+
+func TestOTLPTraceHookExportsBatches(t *testing.T) {
+	var (
+		mutex  sync.Mutex
+		bodies []Map
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		if req.Header.Get("Content-Type") != "application/json" || req.Header.Get("X-Key") != "k" {
+			t.Errorf("unexpected headers: %v", req.Header)
+		}
+		body, _ := io.ReadAll(req.Body)
+		value := Map{}
+		if err := json.Unmarshal(body, &value); err != nil {
+			t.Errorf("invalid body: %v", err)
+		}
+		mutex.Lock()
+		bodies = append(bodies, value)
+		mutex.Unlock()
+	}))
+	defer server.Close()
+
+	h := NewOTLPTraceHook(OTLPConfig{
+		Endpoint:  server.URL,
+		Headers:   map[string]string{"X-Key": "k"},
+		BatchSize: 2,
+		Resource:  Map{"team": "core"},
+	})
+	h.Start()
+
+	meta := NewMeta()
+	outer := h.Begin(meta, "outer", TraceAttrs("infrago", TraceKindMethod, "outer", Map{"step": "server"}))
+	inner := h.Begin(meta, "inner", TraceAttrs("infrago", TraceKindMethod, "inner"))
+	inner.End(Invalid)
+	outer.End()
+	_ = h.Trace(meta, "instant", "", nil)
+	h.Stop()
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	if len(bodies) != 2 {
+		t.Fatalf("expected 2 batches, got %d", len(bodies))
+	}
+
+	var spans []Any
+	for _, body := range bodies {
+		resourceSpans := body["resourceSpans"].([]Any)[0].(map[string]Any)
+		attrs := resourceSpans["resource"].(map[string]Any)["attributes"].([]Any)
+		found := false
+		for _, attr := range attrs {
+			if attr.(map[string]Any)["key"] == "team" {
+				found = true
+			}
+		}
+		if !found {
+			t.Fatalf("expected resource attribute in %v", attrs)
+		}
+		scope := resourceSpans["scopeSpans"].([]Any)[0].(map[string]Any)
+		spans = append(spans, scope["spans"].([]Any)...)
+	}
+	if len(spans) != 3 {
+		t.Fatalf("expected 3 spans, got %d", len(spans))
+	}
+
+	first := spans[0].(map[string]Any)
+	second := spans[1].(map[string]Any)
+	if first["name"] != "method:inner" || first["parentSpanId"] != second["spanId"] {
+		t.Fatalf("unexpected inner span: %v", first)
+	}
+	if first["status"].(map[string]Any)["code"] != float64(otlpStatusError) {
+		t.Fatalf("expected error status: %v", first["status"])
+	}
+	if second["kind"] != float64(otlpKindServer) || second["status"].(map[string]Any)["code"] != float64(otlpStatusOK) {
+		t.Fatalf("unexpected outer span: %v", second)
+	}
+	if _, ok := second["startTimeUnixNano"].(string); !ok {
+		t.Fatalf("expected string timestamps: %v", second)
+	}
+}
+
+func TestOTLPValue(t *testing.T) {
+	if v := otlpValue(int64(3)); v["intValue"] != "3" {
+		t.Fatalf("unexpected int value: %v", v)
+	}
+	if v := otlpValue(true); v["boolValue"] != true {
+		t.Fatalf("unexpected bool value: %v", v)
+	}
+	if v := otlpValue(1.5); v["doubleValue"] != 1.5 {
+		t.Fatalf("unexpected double value: %v", v)
+	}
+}
+
+func TestOTLPTraceHookExportsInlineAfterStop(t *testing.T) {
+	var batches atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		batches.Add(1)
+	}))
+	defer server.Close()
+
+	h := NewOTLPTraceHook(OTLPConfig{Endpoint: server.URL, BatchSize: 2})
+	h.Start()
+	h.Stop()
+
+	meta := NewMeta()
+	_ = h.Trace(meta, "shutdown.a", "", nil)
+	_ = h.Trace(meta, "shutdown.b", "", nil)
+	if batches.Load() != 1 {
+		t.Fatalf("expected a full batch exported inline after Stop, got %d", batches.Load())
+	}
+}
@@ -18,6 +18,7 @@ var (
 
 	varEmpty = Result(7, "varempty", "%s不可为空")
 	varError = Result(8, "varerror", "%s无效")
+	notFound = Result(9, "notfound", "不存在")
 )
 
 type (
@@ -5,12 +5,15 @@ import (
 	"crypto/rand"
 	"encoding/hex"
 	"fmt"
+	"io"
+	"log/slog"
 	"os"
 	"os/signal"
 	"slices"
 	"strings"
 	"sync"
 	"syscall"
+	"time"
 
 	. "github.com/infrago/base"
 )
@@ -53,6 +56,9 @@ type infragoRuntime struct {
 	node          string
 	nodeSet       bool
 	setting       Map
+	baseSetting   Map
+	loaded        Map
+	sources       map[string]string
 
 	overrideStatus bool
 	loadStatus     bool
@@ -61,6 +67,8 @@ type infragoRuntime struct {
 	openStatus     bool
 	startStatus    bool
 	closeStatus    bool
+	readyStatus    bool
+	stopStatus     bool
 }
 
 func (c *infragoRuntime) Name() string {
@@ -197,30 +205,24 @@ func (c *infragoRuntime) runtimeConfig(cfg Map) {
 	if setting, ok := cfg["setting"].(Map); ok {
 		mergeMap(c.setting, setting)
 	}
-	if profile, ok := cfg["profile"].(string); ok {
-		profile = normalizeToken(profile)
+	if profile, role := configProfileRole(cfg); profile != "" || role != "" {
 		if profile != "" {
 			c.configProfile = profile
 		}
-	}
-	if role, ok := cfg["role"].(string); ok {
-		role = normalizeToken(role)
 		if role != "" {
 			c.configRole = role
 		}
 	}
 	if runtimeCfg, ok := cfg["infrago"].(Map); ok {
-		if profile, ok := runtimeCfg["profile"].(string); ok {
-			profile = normalizeToken(profile)
-			if profile != "" {
-				c.configProfile = profile
-			}
-		}
-		if role, ok := runtimeCfg["role"].(string); ok {
-			role = normalizeToken(role)
-			if role != "" {
-				c.configRole = role
+		if keys, ok := runtimeCfg["baggage"].([]Any); ok {
+			allow := make([]string, 0, len(keys))
+			for _, key := range keys {
+				if vv, ok := key.(string); ok {
+					allow = append(allow, vv)
+				}
 			}
+			// baggage = [] clears an earlier list, every key is allowed again.
+			baggageAllow.set(allow)
 		}
 	}
 
@@ -238,11 +240,32 @@ func (c *infragoRuntime) Load() {
 		c.setNode(node)
 	}
 
+	// the secret key is resolved once per load, for --encrypt and enc: values.
+	if err := loadSecretKey(); err != nil {
+		panic(fmt.Errorf("load config failed: %w", err))
+	}
+	// CLI-only modes run in place of the app, this is the only step that exits.
+	if done, err := c.runCommand(os.Args[1:], os.Stdin, os.Stdout); done {
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
 	//从配置模块加载配置
-	cfg, err := hook.LoadConfig()
+	loaded, err := c.prepareConfig()
 	if err != nil {
 		panic(fmt.Errorf("load config failed: %w", err))
 	}
+	cfg, sources := loaded.cfg, loaded.sources
+	configSecrets.set(loaded.secrets)
+
+	c.mutex.Lock()
+	c.baseSetting = cloneSettingMap(c.setting)
+	c.loaded = cloneSettingMap(cfg)
+	c.sources = sources
+	c.mutex.Unlock()
 	c.Config(cfg)
 
 	// ensure node is always available and concise by default.
@@ -259,6 +282,93 @@ func (c *infragoRuntime) Load() {
 	c.loadStatus = true
 }
 
+// runCommand runs a CLI-only mode and reports whether one ran: --encrypt
+// before any config is loaded, --print-config on the config Load would apply.
+// The secret key must be loaded already.
+func (c *infragoRuntime) runCommand(args []string, stdin io.Reader, stdout io.Writer) (bool, error) {
+	if ok, err := secretCommand(args, stdin, stdout); ok {
+		if err != nil {
+			return true, fmt.Errorf("encrypt failed: %w", err)
+		}
+		return true, nil
+	}
+
+	format, ok := printConfigFormat(args)
+	if !ok {
+		return false, nil
+	}
+	loaded, err := c.prepareConfig()
+	if err != nil {
+		return true, fmt.Errorf("load config failed: %w", err)
+	}
+	configSecrets.set(loaded.secrets)
+	if err := printConfig(stdout, loaded.cfg, loaded.sources, format); err != nil {
+		return true, fmt.Errorf("print config failed: %w", err)
+	}
+	return true, nil
+}
+
+// prepareConfig loads and validates config, with sources resolved for every leaf.
+func (c *infragoRuntime) prepareConfig() (*loadedConfig, error) {
+	loaded, err := c.loadConfig()
+	if err != nil {
+		return nil, err
+	}
+	if err := validateConfig(loaded.cfg, c.moduleList(), loaded.sources); err != nil {
+		return nil, err
+	}
+	loaded.sources = resolveConfigSources(loaded.cfg, loaded.sources)
+	return loaded, nil
+}
+
+// loadedConfig is one pass of the load pipeline.
+type loadedConfig struct {
+	cfg     Map
+	secrets []string
+	sources map[string]string
+}
+
+// loadConfig loads config through the config hook, merges the sections of the
+// effective profile and role, applies env and --set overrides, decrypts enc:
+// values, then expands ${...} references, so they see the decrypted and
+// overridden values. Sources follow every step.
+func (c *infragoRuntime) loadConfig() (*loadedConfig, error) {
+	cfg, err := hook.LoadConfig()
+	if err != nil {
+		return nil, err
+	}
+	sources := hook.configSources()
+	overlay, overlaySources := configOverlay()
+	cfg = c.applyConfigOverlays(cfg, overlay, sources)
+	cfg = mergeMap(cfg, overlay)
+	delete(cfg, configProfilesKey)
+	delete(cfg, configRolesKey)
+	for key, source := range overlaySources {
+		sources[key] = source
+	}
+	secrets, err := decryptConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	secrets, err = interpolateConfig(cfg, secrets)
+	if err != nil {
+		return nil, err
+	}
+	return &loadedConfig{cfg: cfg, secrets: secrets, sources: sources}, nil
+}
+
+// ConfigSources returns where each dotted key of the loaded config came from:
+// file:line, env var, --set flag, or default for schema defaults.
+func (c *infragoRuntime) ConfigSources() map[string]string {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	out := make(map[string]string, len(c.sources))
+	for key, source := range c.sources {
+		out[key] = source
+	}
+	return out
+}
+
 // Config applies config to core and all modules.
 func (c *infragoRuntime) Config(cfg Map) {
 	if cfg == nil {
@@ -267,7 +377,7 @@ func (c *infragoRuntime) Config(cfg Map) {
 
 	c.runtimeConfig(cfg)
 	for _, mod := range c.modules {
-		mod.Config(cfg)
+		c.lifecycle("config", mod, func() { mod.Config(cfg) })
 	}
 }
 
@@ -277,7 +387,7 @@ func (c *infragoRuntime) Setup() {
 		return
 	}
 	for _, mod := range c.modules {
-		mod.Setup()
+		c.lifecycle("setup", mod, mod.Setup)
 	}
 	c.setupStatus = true
 	c.closeStatus = false
@@ -289,7 +399,7 @@ func (c *infragoRuntime) Open() {
 		return
 	}
 	for _, mod := range c.modules {
-		mod.Open()
+		c.lifecycle("open", mod, mod.Open)
 	}
 	c.openStatus = true
 }
@@ -300,15 +410,20 @@ func (c *infragoRuntime) Start() {
 		return
 	}
 	for _, mod := range c.modules {
-		mod.Start()
+		c.lifecycle("start", mod, mod.Start)
 	}
 	// Trigger START after all modules are started.
 	// This must stay in runtime (not triggerModule.Start), otherwise the
 	// trigger can fire before late modules (e.g. bus) are fully ready.
 	trigger.Toggle(START)
 
+	c.mutex.Lock()
+	c.readyStatus = true
+	c.stopStatus = false
+	c.mutex.Unlock()
+
 	project, role, profile, node := c.runtimeInfo()
-	fmt.Printf("infrago started: project=%s role=%s profile=%s node=%s\n", project, role, profile, node)
+	hook.Log(nil, slog.LevelInfo, "infrago started", "project", project, "role", role, "profile", profile, "node", node)
 
 	c.startStatus = true
 }
@@ -318,13 +433,19 @@ func (c *infragoRuntime) Stop() {
 	if !c.startStatus {
 		return
 	}
+	// report not-ready first, so balancers drain the node before shutdown.
+	c.mutex.Lock()
+	c.readyStatus = false
+	c.stopStatus = true
+	c.mutex.Unlock()
+
 	// Trigger STOP before module shutdown, so handlers can still use modules
 	// like bus/log while they are alive.
 	// This is centralized here for deterministic lifecycle ordering.
 	trigger.SyncToggle(STOP)
 	// stop the modules in reverse order
 	for i := len(c.modules) - 1; i >= 0; i-- {
-		c.modules[i].Stop()
+		c.lifecycle("stop", c.modules[i], c.modules[i].Stop)
 	}
 	c.startStatus = false
 }
@@ -337,12 +458,12 @@ func (c *infragoRuntime) Close() {
 	project, role, profile, node := c.runtimeInfo()
 	// close the modules in reverse order
 	for i := len(c.modules) - 1; i >= 0; i-- {
-		c.modules[i].Close()
+		c.lifecycle("close", c.modules[i], c.modules[i].Close)
 	}
 	c.closeStatus = true
 	c.openStatus = false
 	c.setupStatus = false
-	fmt.Printf("infrago stopped: project=%s role=%s profile=%s node=%s\n", project, role, profile, node)
+	hook.Log(nil, slog.LevelInfo, "infrago stopped", "project", project, "role", role, "profile", profile, "node", node)
 }
 
 // Wait blocks until system termination signal.
@@ -362,6 +483,37 @@ func (c *infragoRuntime) Override(args ...bool) bool {
 	return c.overrideStatus
 }
 
+// lifecycle runs one module phase and reports how long it took.
+func (c *infragoRuntime) lifecycle(phase string, mod Module, fn func()) {
+	begin := time.Now()
+	fn()
+	hook.Gauge("infrago_module_lifecycle_seconds", map[string]string{
+		"module": fmt.Sprintf("%T", mod),
+		"phase":  phase,
+	}, time.Since(begin).Seconds())
+}
+
+// readiness reports whether the runtime finished Start and has not begun Stop.
+func (c *infragoRuntime) readiness() (bool, error) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	if c.readyStatus {
+		return true, nil
+	}
+	if c.stopStatus {
+		return false, errRuntimeStopping
+	}
+	return false, errRuntimeNotReady
+}
+
+func (c *infragoRuntime) moduleList() []Module {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	out := make([]Module, len(c.modules))
+	copy(out, c.modules)
+	return out
+}
+
 func (c *infragoRuntime) runtimeInfo() (string, string, string, string) {
 	c.mutex.RLock()
 	project := c.project
@@ -391,27 +543,36 @@ func (c *infragoRuntime) EffectiveProfiles() []string {
 }
 
 func (c *infragoRuntime) effectiveProfilesLocked() []string {
-	// priority: env > config > Run(profile) > global
+	return selectProfiles(c.configProfile, c.runProfiles)
+}
+
+func (c *infragoRuntime) effectiveRoleLocked(profile string) string {
+	return selectRole(c.configRole, profile)
+}
+
+// selectProfiles picks profiles by priority: env > config > Run(profile) > global.
+func selectProfiles(configProfile string, runProfiles []string) []string {
 	if profile, ok := bootstrapProfile(); ok {
 		return []string{profile}
 	}
-	if c.configProfile != "" {
-		return []string{c.configProfile}
+	if configProfile != "" {
+		return []string{configProfile}
 	}
-	if len(c.runProfiles) > 0 {
-		out := make([]string, len(c.runProfiles))
-		copy(out, c.runProfiles)
+	if len(runProfiles) > 0 {
+		out := make([]string, len(runProfiles))
+		copy(out, runProfiles)
 		return out
 	}
 	return []string{GLOBAL}
 }
 
-func (c *infragoRuntime) effectiveRoleLocked(profile string) string {
+// selectRole picks the role by priority: env/flag > config > profile > global.
+func selectRole(configRole, profile string) string {
 	if role, ok := bootstrapRole(); ok {
 		return role
 	}
-	if c.configRole != "" {
-		return c.configRole
+	if configRole != "" {
+		return configRole
 	}
 	if profile != "" {
 		return profile
@@ -1,13 +1,37 @@
 package infra
 
+import (
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	. "github.com/infrago/base"
+)
+
+// StatsLatencyBuckets are the upper bounds (ms) of ServiceStats.Latency.
+// The histogram has one extra trailing slot for slower calls.
+var StatsLatencyBuckets = []int64{1, 2, 5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000, 10000}
+
+var stats = &statsCollector{
+	entries: make(map[string]*ServiceStats, 0),
+}
+
 // ServiceStats contains service statistics.
 type ServiceStats struct {
-	Name         string `json:"name"`
-	Version      string `json:"version"`
-	NumRequests  int    `json:"num_requests"`
-	NumErrors    int    `json:"num_errors"`
-	TotalLatency int64  `json:"total_latency_ms"`
-	AvgLatency   int64  `json:"avg_latency_ms"`
+	Name         string         `json:"name"`
+	Version      string         `json:"version"`
+	Kind         string         `json:"kind,omitempty"`
+	NumRequests  int            `json:"num_requests"`
+	NumErrors    int            `json:"num_errors"`
+	Errors       map[string]int `json:"errors,omitempty"`
+	TotalLatency int64          `json:"total_latency_ms"`
+	AvgLatency   int64          `json:"avg_latency_ms"`
+	MaxLatency   int64          `json:"max_latency_ms"`
+	P50Latency   int64          `json:"p50_latency_ms"`
+	P90Latency   int64          `json:"p90_latency_ms"`
+	P99Latency   int64          `json:"p99_latency_ms"`
+	Latency      []int          `json:"latency_buckets,omitempty"`
 }
 
 // NodeInfo contains one online node's exposed service set.
@@ -36,3 +60,206 @@ type ServiceInfo struct {
 	Instances int           `json:"instances"`
 	Nodes     []ServiceNode `json:"nodes"`
 }
+
+type statsCollector struct {
+	mutex   sync.Mutex
+	entries map[string]*ServiceStats
+}
+
+// Record adds one finished invocation into the collector.
+func (s *statsCollector) Record(kind, name string, res Res, elapsed time.Duration) {
+	if name == "" {
+		return
+	}
+	latency := elapsed.Milliseconds()
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	key := kind + ":" + name
+	entry, ok := s.entries[key]
+	if !ok {
+		entry = &ServiceStats{
+			Name:    name,
+			Kind:    kind,
+			Latency: make([]int, len(StatsLatencyBuckets)+1),
+		}
+		s.entries[key] = entry
+	}
+
+	entry.NumRequests++
+	if res != nil && res.Fail() {
+		entry.NumErrors++
+		if entry.Errors == nil {
+			entry.Errors = make(map[string]int, 0)
+		}
+		entry.Errors[statsStatus(res)]++
+	}
+	entry.TotalLatency += latency
+	if latency > entry.MaxLatency {
+		entry.MaxLatency = latency
+	}
+	entry.Latency[statsBucket(latency)]++
+}
+
+// Stats returns a snapshot of all collected entries, sorted by kind and name.
+func (s *statsCollector) Stats() []ServiceStats {
+	s.mutex.Lock()
+	out := make([]ServiceStats, 0, len(s.entries))
+	for _, entry := range s.entries {
+		out = append(out, cloneServiceStats(*entry))
+	}
+	s.mutex.Unlock()
+
+	for i := range out {
+		finishServiceStats(&out[i])
+	}
+	sortServiceStats(out)
+	return out
+}
+
+// Reset drops all collected entries.
+func (s *statsCollector) Reset() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.entries = make(map[string]*ServiceStats, 0)
+}
+
+// CoreStats returns statistics recorded by core for every local invocation path.
+func CoreStats() []ServiceStats {
+	return stats.Stats()
+}
+
+// MergeServiceStats merges several stats lists by kind and name.
+// Bus hooks use it to combine their own numbers with CoreStats.
+func MergeServiceStats(groups ...[]ServiceStats) []ServiceStats {
+	merged := make(map[string]*ServiceStats, 0)
+	for _, group := range groups {
+		for _, item := range group {
+			key := item.Kind + ":" + item.Name
+			current, ok := merged[key]
+			if !ok {
+				next := cloneServiceStats(item)
+				if len(next.Latency) != len(StatsLatencyBuckets)+1 {
+					next.Latency = nil
+				}
+				merged[key] = &next
+				continue
+			}
+			if current.Version == "" {
+				current.Version = item.Version
+			}
+			current.NumRequests += item.NumRequests
+			current.NumErrors += item.NumErrors
+			current.TotalLatency += item.TotalLatency
+			if item.MaxLatency > current.MaxLatency {
+				current.MaxLatency = item.MaxLatency
+			}
+			for status, count := range item.Errors {
+				if current.Errors == nil {
+					current.Errors = make(map[string]int, 0)
+				}
+				current.Errors[status] += count
+			}
+			if current.Latency != nil && len(item.Latency) == len(current.Latency) {
+				for i, count := range item.Latency {
+					current.Latency[i] += count
+				}
+			} else {
+				// one side has no histogram, keep the pessimistic percentiles.
+				current.Latency = nil
+				current.P50Latency = max(current.P50Latency, item.P50Latency)
+				current.P90Latency = max(current.P90Latency, item.P90Latency)
+				current.P99Latency = max(current.P99Latency, item.P99Latency)
+			}
+		}
+	}
+
+	out := make([]ServiceStats, 0, len(merged))
+	for _, item := range merged {
+		finishServiceStats(item)
+		out = append(out, *item)
+	}
+	sortServiceStats(out)
+	return out
+}
+
+func finishServiceStats(item *ServiceStats) {
+	item.AvgLatency = 0
+	if item.NumRequests > 0 {
+		item.AvgLatency = item.TotalLatency / int64(item.NumRequests)
+	}
+	if len(item.Latency) == len(StatsLatencyBuckets)+1 {
+		item.P50Latency = statsPercentile(item.Latency, item.MaxLatency, 0.50)
+		item.P90Latency = statsPercentile(item.Latency, item.MaxLatency, 0.90)
+		item.P99Latency = statsPercentile(item.Latency, item.MaxLatency, 0.99)
+	}
+}
+
+func cloneServiceStats(in ServiceStats) ServiceStats {
+	out := in
+	if in.Errors != nil {
+		out.Errors = make(map[string]int, len(in.Errors))
+		for k, v := range in.Errors {
+			out.Errors[k] = v
+		}
+	}
+	if in.Latency != nil {
+		out.Latency = make([]int, len(in.Latency))
+		copy(out.Latency, in.Latency)
+	}
+	return out
+}
+
+func sortServiceStats(items []ServiceStats) {
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].Kind != items[j].Kind {
+			return items[i].Kind < items[j].Kind
+		}
+		return items[i].Name < items[j].Name
+	})
+}
+
+func statsBucket(latency int64) int {
+	for i, bound := range StatsLatencyBuckets {
+		if latency <= bound {
+			return i
+		}
+	}
+	return len(StatsLatencyBuckets)
+}
+
+// statsPercentile returns the upper bound of the bucket holding the p-th call,
+// capped by the observed max latency.
+func statsPercentile(buckets []int, maxLatency int64, p float64) int64 {
+	total := 0
+	for _, count := range buckets {
+		total += count
+	}
+	if total == 0 {
+		return 0
+	}
+	rank := int(float64(total)*p + 0.999999)
+	if rank < 1 {
+		rank = 1
+	}
+	seen := 0
+	for i, count := range buckets {
+		seen += count
+		if seen < rank {
+			continue
+		}
+		if i < len(StatsLatencyBuckets) && StatsLatencyBuckets[i] < maxLatency {
+			return StatsLatencyBuckets[i]
+		}
+		return maxLatency
+	}
+	return maxLatency
+}
+
+func statsStatus(res Res) string {
+	if status := res.Status(); status != "" {
+		return status
+	}
+	return strconv.Itoa(res.Code())
+}
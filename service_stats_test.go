@@ -0,0 +1,73 @@
+package infra
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStatsCollectorRecordsCountsAndErrors(t *testing.T) {
+	collector := &statsCollector{entries: make(map[string]*ServiceStats, 0)}
+	collector.Record(coreKindService, "demo.get", OK, 3*time.Millisecond)
+	collector.Record(coreKindService, "demo.get", Invalid, 8*time.Millisecond)
+	collector.Record(coreKindService, "demo.get", Invalid, 40*time.Millisecond)
+	collector.Record(coreKindMethod, "demo.get", nil, time.Millisecond)
+
+	items := collector.Stats()
+	if len(items) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(items))
+	}
+	svc := items[1]
+	if svc.Kind != coreKindService || svc.NumRequests != 3 || svc.NumErrors != 2 {
+		t.Fatalf("unexpected service stats: %#v", svc)
+	}
+	if svc.Errors[Invalid.Status()] != 2 {
+		t.Fatalf("expected errors by status, got %#v", svc.Errors)
+	}
+	if svc.TotalLatency != 51 || svc.AvgLatency != 17 || svc.MaxLatency != 40 {
+		t.Fatalf("unexpected latency: %#v", svc)
+	}
+	if svc.P50Latency != 10 || svc.P99Latency != 40 {
+		t.Fatalf("unexpected percentiles p50=%d p99=%d", svc.P50Latency, svc.P99Latency)
+	}
+}
+
+func TestMergeServiceStatsSumsHistograms(t *testing.T) {
+	left := &statsCollector{entries: make(map[string]*ServiceStats, 0)}
+	right := &statsCollector{entries: make(map[string]*ServiceStats, 0)}
+	for i := 0; i < 9; i++ {
+		left.Record(coreKindService, "demo.get", OK, time.Millisecond)
+	}
+	right.Record(coreKindService, "demo.get", Fail, 900*time.Millisecond)
+
+	merged := MergeServiceStats(left.Stats(), right.Stats(), []ServiceStats{{Name: "remote.only", NumRequests: 4}})
+	if len(merged) != 2 {
+		t.Fatalf("expected 2 merged entries, got %d", len(merged))
+	}
+	item := merged[1]
+	if item.NumRequests != 10 || item.NumErrors != 1 || item.Errors[Fail.Status()] != 1 {
+		t.Fatalf("unexpected merged counts: %#v", item)
+	}
+	if item.P50Latency != 1 || item.P99Latency != 900 {
+		t.Fatalf("unexpected merged percentiles p50=%d p99=%d", item.P50Latency, item.P99Latency)
+	}
+	if merged[0].Name != "remote.only" || merged[0].AvgLatency != 0 {
+		t.Fatalf("unexpected passthrough entry: %#v", merged[0])
+	}
+}
+
+func TestInvokeRecordsInProcessMissAsNotFound(t *testing.T) {
+	originalCore, originalHook, originalStats := core, hook, stats
+	core = &coreModule{entries: map[string]coreEntry{}}
+	hook = &infragoHook{}
+	hook.AttachBus(&defaultBusHook{})
+	stats = &statsCollector{entries: make(map[string]*ServiceStats, 0)}
+	defer func() { core, hook, stats = originalCore, originalHook, originalStats }()
+
+	if data, res := core.Invoke(nil, "demo.missing", nil); data != nil || res == nil || !res.OK() {
+		t.Fatalf("expected bus result passed through, got %v %v", data, res)
+	}
+	items := stats.Stats()
+	if len(items) != 1 || items[0].NumErrors != 1 || items[0].Errors[notFound.Status()] != 1 {
+		t.Fatalf("expected miss recorded as not found, got %#v", items)
+	}
+}
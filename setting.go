@@ -1,6 +1,15 @@
 package infra
 
-import . "github.com/infrago/base"
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	. "github.com/infrago/base"
+)
 
 func (c *infragoRuntime) Setting() Map {
 	c.mutex.RLock()
@@ -56,3 +65,388 @@ func mergeMap(dst, src Map) Map {
 	}
 	return dst
 }
+
+// settingValue finds path in setting, see lookupSetting.
+func (c *infragoRuntime) settingValue(path string) (Any, bool) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return lookupSetting(c.setting, path)
+}
+
+// lookupSetting resolves a dotted path, keys compare case-insensitively.
+// A key that itself contains dots, like "token.idLength", is tried before
+// descending, at every level.
+func lookupSetting(m Map, path string) (Any, bool) {
+	if value, ok := settingKey(m, path); ok {
+		return value, true
+	}
+	for i := 0; i < len(path); i++ {
+		if path[i] != '.' {
+			continue
+		}
+		if next, ok := settingKey(m, path[:i]); ok {
+			if child, ok := next.(Map); ok {
+				if value, ok := lookupSetting(child, path[i+1:]); ok {
+					return value, true
+				}
+			}
+		}
+	}
+	return nil, false
+}
+
+// settingKey prefers the exact key, else the first case-insensitive match in
+// sorted order, so idLength wins over idlength whatever the map order.
+func settingKey(m Map, key string) (Any, bool) {
+	if value, ok := m[key]; ok {
+		return value, true
+	}
+	names := make([]string, 0)
+	for name := range m {
+		if strings.EqualFold(name, key) {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return nil, false
+	}
+	sort.Strings(names)
+	return m[names[0]], true
+}
+
+func (c *infragoRuntime) SettingString(path string, def string) string {
+	if value, ok := c.settingValue(path); ok {
+		if vv, ok := settingString(value); ok {
+			return vv
+		}
+	}
+	return def
+}
+
+func (c *infragoRuntime) SettingInt(path string, def int) int {
+	if value, ok := c.settingValue(path); ok {
+		if vv, ok := settingInt64(value); ok {
+			return int(vv)
+		}
+	}
+	return def
+}
+
+func (c *infragoRuntime) SettingDuration(path string, def time.Duration) time.Duration {
+	if value, ok := c.settingValue(path); ok {
+		if vv, ok := settingDuration(value); ok {
+			return vv
+		}
+	}
+	return def
+}
+
+func (c *infragoRuntime) SettingBool(path string, def bool) bool {
+	if value, ok := c.settingValue(path); ok {
+		if vv, ok := settingBool(value); ok {
+			return vv
+		}
+	}
+	return def
+}
+
+func (c *infragoRuntime) SettingSlice(path string, def []Any) []Any {
+	if value, ok := c.settingValue(path); ok {
+		if vv, ok := settingSlice(value); ok {
+			return vv
+		}
+	}
+	return def
+}
+
+// DecodeSetting fills the struct out points to from the setting section at path,
+// the whole setting when path is empty. See decodeSetting for field mapping.
+func (c *infragoRuntime) DecodeSetting(path string, out Any) error {
+	c.mutex.RLock()
+	var section Any = c.setting
+	ok := true
+	if path != "" {
+		section, ok = lookupSetting(c.setting, path)
+	}
+	section = cloneSettingValue(section)
+	c.mutex.RUnlock()
+
+	target := reflect.ValueOf(out)
+	if target.Kind() != reflect.Pointer || target.IsNil() {
+		return fmt.Errorf("decode setting %s: out must be a non-nil pointer, got %T", path, out)
+	}
+	if !ok {
+		return nil
+	}
+	return decodeSetting(path, section, target.Elem())
+}
+
+// settingString converts text and scalars to a string.
+func settingString(value Any) (string, bool) {
+	switch v := value.(type) {
+	case string:
+		return v, true
+	case []byte:
+		return string(v), true
+	case bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64, time.Duration:
+		return fmt.Sprint(v), true
+	default:
+		return "", false
+	}
+}
+
+// settingInt reads an int setting as settingInt64 does, 0 when it is not one.
+func settingInt(value Any) int {
+	if v, ok := settingInt64(value); ok {
+		return int(v)
+	}
+	return 0
+}
+
+// settingInt64 converts numbers and numeric text, floats only when integral.
+func settingInt64(value Any) (int64, bool) {
+	switch v := value.(type) {
+	case float32:
+		return int64(v), v == float32(int64(v))
+	case float64:
+		return int64(v), v == float64(int64(v))
+	case string:
+		text := strings.TrimSpace(v)
+		if n, err := strconv.ParseInt(text, 10, 64); err == nil {
+			return n, true
+		}
+		if f, err := strconv.ParseFloat(text, 64); err == nil && f == float64(int64(f)) {
+			return int64(f), true
+		}
+		return 0, false
+	case time.Duration:
+		return int64(v), true
+	}
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int(), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int64(rv.Uint()), true
+	}
+	return 0, false
+}
+
+func settingFloat(value Any) (float64, bool) {
+	switch v := value.(type) {
+	case float32:
+		return float64(v), true
+	case float64:
+		return v, true
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		return f, err == nil
+	}
+	n, ok := settingInt64(value)
+	return float64(n), ok
+}
+
+// settingDuration reads durations and text like "5s", plain numbers count seconds.
+func settingDuration(value Any) (time.Duration, bool) {
+	if d, ok := configDuration(value); ok {
+		return d, true
+	}
+	if f, ok := settingFloat(value); ok {
+		return time.Duration(f * float64(time.Second)), true
+	}
+	return 0, false
+}
+
+// settingBool reads bools, on/off and yes/no text, and numbers as non-zero.
+func settingBool(value Any) (bool, bool) {
+	switch v := value.(type) {
+	case bool:
+		return v, true
+	case string:
+		switch strings.ToLower(strings.TrimSpace(v)) {
+		case "true", "t", "1", "yes", "y", "on":
+			return true, true
+		case "false", "f", "0", "no", "n", "off", "":
+			return false, true
+		}
+		return false, false
+	}
+	if f, ok := settingFloat(value); ok {
+		return f != 0, true
+	}
+	return false, false
+}
+
+// settingSlice copies lists of any element type, a single value becomes a list of one.
+func settingSlice(value Any) ([]Any, bool) {
+	switch v := value.(type) {
+	case nil:
+		return nil, false
+	case []Any:
+		return cloneSettingValue(v).([]Any), true
+	case string:
+		return []Any{v}, true
+	}
+	rv := reflect.ValueOf(value)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return []Any{value}, true
+	}
+	out := make([]Any, rv.Len())
+	for i := range out {
+		out[i] = cloneSettingValue(rv.Index(i).Interface())
+	}
+	return out, true
+}
+
+// decodeSetting assigns value to target. Struct fields are matched by the
+// `setting` tag, then the `json` tag, then the field name, all case-insensitive;
+// a "-" tag skips the field. Scalars go through the same coercion as the
+// Setting* accessors.
+func decodeSetting(path string, value Any, target reflect.Value) error {
+	if value == nil {
+		return nil
+	}
+	if target.Kind() == reflect.Pointer {
+		if target.IsNil() {
+			target.Set(reflect.New(target.Type().Elem()))
+		}
+		return decodeSetting(path, value, target.Elem())
+	}
+
+	mismatch := func() error {
+		return fmt.Errorf("setting %s: cannot use %T as %s", path, value, target.Type())
+	}
+	switch target.Type() {
+	case reflect.TypeOf(time.Duration(0)):
+		d, ok := settingDuration(value)
+		if !ok {
+			return mismatch()
+		}
+		target.SetInt(int64(d))
+		return nil
+	case reflect.TypeOf(time.Time{}):
+		switch v := value.(type) {
+		case time.Time:
+			target.Set(reflect.ValueOf(v))
+		case string:
+			tm, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				return mismatch()
+			}
+			target.Set(reflect.ValueOf(tm))
+		default:
+			return mismatch()
+		}
+		return nil
+	}
+
+	switch target.Kind() {
+	case reflect.String:
+		v, ok := settingString(value)
+		if !ok {
+			return mismatch()
+		}
+		target.SetString(v)
+	case reflect.Bool:
+		v, ok := settingBool(value)
+		if !ok {
+			return mismatch()
+		}
+		target.SetBool(v)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		v, ok := settingInt64(value)
+		if !ok || target.OverflowInt(v) {
+			return mismatch()
+		}
+		target.SetInt(v)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		v, ok := settingInt64(value)
+		if !ok || v < 0 || target.OverflowUint(uint64(v)) {
+			return mismatch()
+		}
+		target.SetUint(uint64(v))
+	case reflect.Float32, reflect.Float64:
+		v, ok := settingFloat(value)
+		if !ok {
+			return mismatch()
+		}
+		target.SetFloat(v)
+	case reflect.Slice:
+		items, ok := settingSlice(value)
+		if !ok {
+			return mismatch()
+		}
+		out := reflect.MakeSlice(target.Type(), len(items), len(items))
+		for i, item := range items {
+			if err := decodeSetting(path+"."+strconv.Itoa(i), item, out.Index(i)); err != nil {
+				return err
+			}
+		}
+		target.Set(out)
+	case reflect.Map:
+		m, ok := value.(Map)
+		if !ok || target.Type().Key().Kind() != reflect.String {
+			return mismatch()
+		}
+		out := reflect.MakeMapWithSize(target.Type(), len(m))
+		for key, item := range m {
+			elem := reflect.New(target.Type().Elem()).Elem()
+			if err := decodeSetting(path+"."+key, item, elem); err != nil {
+				return err
+			}
+			out.SetMapIndex(reflect.ValueOf(key).Convert(target.Type().Key()), elem)
+		}
+		target.Set(out)
+	case reflect.Struct:
+		m, ok := value.(Map)
+		if !ok {
+			return mismatch()
+		}
+		return decodeSettingStruct(path, m, target)
+	case reflect.Interface:
+		if !reflect.TypeOf(value).AssignableTo(target.Type()) {
+			return mismatch()
+		}
+		target.Set(reflect.ValueOf(value))
+	default:
+		return mismatch()
+	}
+	return nil
+}
+
+func decodeSettingStruct(path string, m Map, target reflect.Value) error {
+	prefix := path
+	if prefix != "" {
+		prefix += "."
+	}
+	kind := target.Type()
+	for i := 0; i < kind.NumField(); i++ {
+		field := kind.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name := field.Name
+		if tag, ok := field.Tag.Lookup("setting"); ok {
+			name = strings.Split(tag, ",")[0]
+		} else if tag, ok := field.Tag.Lookup("json"); ok && strings.Split(tag, ",")[0] != "" {
+			name = strings.Split(tag, ",")[0]
+		}
+		if name == "-" {
+			continue
+		}
+		if field.Anonymous && field.Type.Kind() == reflect.Struct && name == field.Name {
+			if err := decodeSettingStruct(path, m, target.Field(i)); err != nil {
+				return err
+			}
+			continue
+		}
+		value, ok := settingKey(m, name)
+		if !ok {
+			continue
+		}
+		if err := decodeSetting(prefix+name, value, target.Field(i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -1,7 +1,10 @@
 package infra
 
 import (
+	"fmt"
+	"strings"
 	"testing"
+	"time"
 
 	. "github.com/infrago/base"
 )
@@ -85,3 +88,146 @@ func TestSettingReturnsDeepCopy(t *testing.T) {
 		t.Fatalf("expected nested slice map copy to be isolated from runtime state")
 	}
 }
+
+func TestSettingTypedAccessors(t *testing.T) {
+	rt := &infragoRuntime{setting: Map{
+		"token.idLength": int64(12),
+		"Cache": Map{
+			"TTL":     "1m30s",
+			"Idle":    int64(5),
+			"size":    "64",
+			"ratio":   2.0,
+			"enabled": "on",
+			"hosts":   []string{"a", "b"},
+			"host":    "c",
+		},
+	}}
+
+	if got := rt.SettingInt("token.idlength", 0); got != 12 {
+		t.Fatalf("expected dotted key case-insensitive, got %d", got)
+	}
+	if got := rt.SettingDuration("cache.ttl", 0); got != 90*time.Second {
+		t.Fatalf("unexpected ttl %v", got)
+	}
+	if got := rt.SettingDuration("cache.idle", 0); got != 5*time.Second {
+		t.Fatalf("expected numbers as seconds, got %v", got)
+	}
+	if rt.SettingInt("cache.size", 0) != 64 || rt.SettingInt("cache.ratio", 0) != 2 {
+		t.Fatalf("expected string and integral float coerced to int")
+	}
+	if rt.SettingString("cache.size", "") != "64" || rt.SettingString("cache.ratio", "") != "2" {
+		t.Fatalf("unexpected string coercion")
+	}
+	if !rt.SettingBool("cache.enabled", false) {
+		t.Fatalf("expected on as true")
+	}
+	if hosts := rt.SettingSlice("cache.hosts", nil); len(hosts) != 2 || hosts[1] != "b" {
+		t.Fatalf("unexpected hosts %v", hosts)
+	}
+	if host := rt.SettingSlice("cache.host", nil); len(host) != 1 || host[0] != "c" {
+		t.Fatalf("expected single value as list, got %v", host)
+	}
+
+	if rt.SettingInt("cache.host", 7) != 7 || rt.SettingString("cache.missing", "x") != "x" || rt.SettingBool("cache.size", true) != true {
+		t.Fatalf("expected defaults for missing or unconvertible values")
+	}
+}
+
+func TestDecodeSettingIntoStruct(t *testing.T) {
+	type tls struct {
+		Cert string
+	}
+	type config struct {
+		Host    string        `setting:"host"`
+		Port    uint16        `json:"port"`
+		Timeout time.Duration `setting:"timeout"`
+		Debug   bool
+		Tags    []string
+		Limits  map[string]int
+		TLS     *tls
+		Skip    string `setting:"-"`
+	}
+
+	rt := &infragoRuntime{setting: Map{
+		"server": Map{
+			"HOST":    "localhost",
+			"port":    "8080",
+			"timeout": "2s",
+			"debug":   "yes",
+			"tags":    []Any{"a", "b"},
+			"limits":  Map{"qps": int64(10)},
+			"tls":     Map{"cert": "c.pem"},
+			"skip":    "x",
+		},
+	}}
+
+	cfg := config{}
+	if err := rt.DecodeSetting("server", &cfg); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if cfg.Host != "localhost" || cfg.Port != 8080 || cfg.Timeout != 2*time.Second || !cfg.Debug {
+		t.Fatalf("unexpected scalars %#v", cfg)
+	}
+	if len(cfg.Tags) != 2 || cfg.Limits["qps"] != 10 || cfg.TLS == nil || cfg.TLS.Cert != "c.pem" || cfg.Skip != "" {
+		t.Fatalf("unexpected nested %#v", cfg)
+	}
+
+	rt.setting["server"].(Map)["port"] = int64(70000)
+	err := rt.DecodeSetting("server", &cfg)
+	if err == nil || !strings.Contains(err.Error(), "server.port") {
+		t.Fatalf("expected overflow error naming server.port, got %v", err)
+	}
+	if err := rt.DecodeSetting("server", cfg); err == nil {
+		t.Fatalf("expected error for non-pointer")
+	}
+
+	var typed struct {
+		Name  fmt.Stringer
+		Items []Any
+	}
+	rt.setting["typed"] = Map{"name": "plain", "items": []Any{"a", nil}}
+	err = rt.DecodeSetting("typed", &typed)
+	if err == nil || !strings.Contains(err.Error(), "typed.Name") {
+		t.Fatalf("expected mismatch for fmt.Stringer, got %v", err)
+	}
+	delete(rt.setting["typed"].(Map), "name")
+	if err := rt.DecodeSetting("typed", &typed); err != nil || len(typed.Items) != 2 || typed.Items[1] != nil {
+		t.Fatalf("expected nil list item kept, got %#v %v", typed.Items, err)
+	}
+}
+
+func TestSettingIntMatchesTypedAccessor(t *testing.T) {
+	for _, value := range []Any{"8080", int64(8080), 8080.0, uint16(8080)} {
+		if got := settingInt(value); got != 8080 {
+			t.Fatalf("expected %#v as 8080, got %d", value, got)
+		}
+	}
+	for _, value := range []Any{"http", 1.5, true, nil} {
+		if got := settingInt(value); got != 0 {
+			t.Fatalf("expected %#v as 0, got %d", value, got)
+		}
+	}
+}
+
+func TestSettingKeyPrefersSortedCaseMatch(t *testing.T) {
+	rt := &infragoRuntime{setting: Map{"Size": int64(1), "SIZE": int64(2), "size": int64(3)}}
+	for i := 0; i < 20; i++ {
+		if got := rt.SettingInt("sIZE", 0); got != 2 {
+			t.Fatalf("expected first sorted match SIZE, got %d", got)
+		}
+	}
+	if got := rt.SettingInt("size", 0); got != 3 {
+		t.Fatalf("expected exact key first, got %d", got)
+	}
+
+	original := infrago
+	defer func() { infrago = original }()
+	infrago = &infragoRuntime{setting: Map{"token.idLength": int64(0), "token.idlength": int64(24)}}
+	if got := defaultTokenIDLength(); got != 24 {
+		t.Fatalf("expected token.idlength fallback, got %d", got)
+	}
+	infrago = &infragoRuntime{setting: Map{"token.idLength": int64(16), "token.idlength": int64(24)}}
+	if got := defaultTokenIDLength(); got != 16 {
+		t.Fatalf("expected token.idLength first, got %d", got)
+	}
+}
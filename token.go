@@ -222,54 +222,15 @@ func defaultTokenCodec() string {
 }
 
 func defaultTokenIDLength() int {
-	infrago.mutex.RLock()
-	defer infrago.mutex.RUnlock()
-
-	length := settingInt(infrago.setting["token.idLength"])
+	length := infrago.SettingInt("token.idLength", 0)
 	if length <= 0 {
-		length = settingInt(infrago.setting["token.idlength"])
+		length = infrago.SettingInt("token.idlength", 0)
 	}
 	return normalizeTokenIDLength(length)
 }
 
 func defaultTokenSetting(key string) string {
-	infrago.mutex.RLock()
-	defer infrago.mutex.RUnlock()
-	if v, ok := infrago.setting[key].(string); ok {
-		return v
-	}
-	return ""
-}
-
-func settingInt(v Any) int {
-	switch vv := v.(type) {
-	case int:
-		return vv
-	case int8:
-		return int(vv)
-	case int16:
-		return int(vv)
-	case int32:
-		return int(vv)
-	case int64:
-		return int(vv)
-	case uint:
-		return int(vv)
-	case uint8:
-		return int(vv)
-	case uint16:
-		return int(vv)
-	case uint32:
-		return int(vv)
-	case uint64:
-		return int(vv)
-	case float32:
-		return int(vv)
-	case float64:
-		return int(vv)
-	default:
-		return 0
-	}
+	return infrago.SettingString(key, "")
 }
 
 func defaultTokenHMACSign(data string, key string) (string, error) {
@@ -1,8 +1,12 @@
 package infra
 
-import "strings"
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
 
-import . "github.com/infrago/base"
+	. "github.com/infrago/base"
+)
 
 const (
 	TraceKindMethod  = "method"
@@ -16,6 +20,13 @@ const (
 	TraceKindCustom  = "custom"
 )
 
+const (
+	traceFlagSampled byte = 0x01
+
+	traceStateMaxMembers = 32
+	traceStateMaxValue   = 256
+)
+
 // TraceAttrs builds a normalized trace attrs map.
 func TraceAttrs(service, kind, entry string, attrs ...Map) Map {
 	k, e := normalizeTraceKindEntry(kind, entry)
@@ -55,3 +66,102 @@ func normalizeTraceKindEntry(kind, entry string) (string, string) {
 		return TraceKindCustom, e
 	}
 }
+
+// parseTraceState validates a W3C tracestate list and returns it normalized.
+// Any invalid or duplicated member invalidates the whole header.
+func parseTraceState(header string) (string, bool) {
+	header = strings.TrimSpace(header)
+	if header == "" {
+		return "", true
+	}
+	seen := make(map[string]struct{}, 0)
+	members := make([]string, 0)
+	for _, member := range strings.Split(header, ",") {
+		member = strings.Trim(member, " \t")
+		if member == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(member, "=")
+		if !ok || !validTraceStateKey(key) || !validTraceStateValue(value) {
+			return "", false
+		}
+		if _, ok := seen[key]; ok {
+			return "", false
+		}
+		seen[key] = struct{}{}
+		members = append(members, key+"="+value)
+	}
+	if len(members) > traceStateMaxMembers {
+		return "", false
+	}
+	return strings.Join(members, ","), true
+}
+
+func validTraceStateKey(key string) bool {
+	tenant, system, multi := strings.Cut(key, "@")
+	if !multi {
+		return len(key) <= 256 && validTraceStateKeyPart(key, true)
+	}
+	return len(tenant) <= 241 && len(system) <= 14 &&
+		validTraceStateKeyPart(tenant, false) && validTraceStateKeyPart(system, true)
+}
+
+func validTraceStateKeyPart(key string, alphaFirst bool) bool {
+	if key == "" {
+		return false
+	}
+	for i := 0; i < len(key); i++ {
+		c := key[i]
+		switch {
+		case c >= 'a' && c <= 'z':
+		case c >= '0' && c <= '9':
+			if i == 0 && alphaFirst {
+				return false
+			}
+		case c == '_' || c == '-' || c == '*' || c == '/':
+			if i == 0 {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func validTraceStateValue(value string) bool {
+	if value == "" || len(value) > traceStateMaxValue || value[len(value)-1] == ' ' {
+		return false
+	}
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		if c < 0x20 || c > 0x7e || c == ',' || c == '=' {
+			return false
+		}
+	}
+	return true
+}
+
+// newTraceID returns a random non-zero 16-byte trace id in hex.
+func newTraceID() string {
+	return randomHexID(16)
+}
+
+// newSpanID returns a random non-zero 8-byte span id in hex.
+func newSpanID() string {
+	return randomHexID(8)
+}
+
+func randomHexID(size int) string {
+	raw := make([]byte, size)
+	if _, err := rand.Read(raw); err != nil {
+		return normalizeGeneratedTokenID(Generate(), size*2)
+	}
+	raw[0] |= 0x01
+	return hex.EncodeToString(raw)
+}
+
+// beginUnsampledSpan keeps span ids moving for propagation without recording.
+func beginUnsampledSpan(meta *Meta) TraceSpan {
+	return beginMetaSpan(meta, "", nil, nil)
+}
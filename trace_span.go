@@ -0,0 +1,369 @@
+package infra
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	. "github.com/infrago/base"
+)
+
+const defaultTraceBuffer = 256
+
+var defaultTrace = &defaultTraceHook{size: defaultTraceBuffer}
+
+type (
+	// SpanRecord is one finished span.
+	SpanRecord struct {
+		TraceId      string      `json:"trace_id"`
+		SpanId       string      `json:"span_id"`
+		ParentSpanId string      `json:"parent_span_id,omitempty"`
+		Name         string      `json:"name"`
+		Kind         string      `json:"kind,omitempty"`
+		Entry        string      `json:"entry,omitempty"`
+		Start        time.Time   `json:"start"`
+		End          time.Time   `json:"end"`
+		Duration     int64       `json:"duration_us"`
+		Code         int         `json:"code"`
+		Status       string      `json:"status,omitempty"`
+		Error        string      `json:"error,omitempty"`
+		Attrs        Map         `json:"attrs,omitempty"`
+		Events       []SpanEvent `json:"events,omitempty"`
+		Links        []SpanLink  `json:"links,omitempty"`
+	}
+
+	// SpanEvent is a timestamped annotation inside a span.
+	SpanEvent struct {
+		Name  string    `json:"name"`
+		Time  time.Time `json:"time"`
+		Attrs Map       `json:"attrs,omitempty"`
+	}
+
+	// SpanLink points to a span in another trace or branch, such as the producer of a job.
+	SpanLink struct {
+		TraceId string `json:"trace_id"`
+		SpanId  string `json:"span_id"`
+		Attrs   Map    `json:"attrs,omitempty"`
+	}
+
+	// Span is the span core opened for the current invocation.
+	// Annotations are dropped when the trace hook's span does not support them.
+	Span struct {
+		span TraceSpan
+	}
+
+	// metaSpan moves meta to a child span on begin and restores it on end.
+	metaSpan struct {
+		once   sync.Once
+		mutex  sync.Mutex
+		ended  bool
+		meta   *Meta
+		record SpanRecord
+		finish func(SpanRecord)
+	}
+
+	// defaultTraceHook records finished spans into a bounded ring buffer,
+	// and optionally appends them as JSON lines to [infrago.trace] file.
+	defaultTraceHook struct {
+		mutex sync.Mutex
+		size  int
+		spans []SpanRecord
+		next  int
+		full  bool
+
+		file   string
+		writer *os.File
+	}
+)
+
+func beginMetaSpan(meta *Meta, name string, attrs Map, finish func(SpanRecord)) *metaSpan {
+	kind, _ := attrs["kind"].(string)
+	entry, _ := attrs["entry"].(string)
+	traceId, spanId, parentId := meta.enterSpan(kind, entry)
+
+	span := &metaSpan{
+		meta:   meta,
+		finish: finish,
+		record: SpanRecord{
+			TraceId:      traceId,
+			SpanId:       spanId,
+			ParentSpanId: parentId,
+			Name:         name,
+			Kind:         kind,
+			Entry:        entry,
+			Start:        time.Now(),
+			Attrs:        Map{},
+		},
+	}
+	for k, v := range attrs {
+		span.record.Attrs[k] = v
+	}
+	return span
+}
+
+// End finishes the span once, a Res or error argument sets its status.
+func (s *metaSpan) End(args ...Any) {
+	s.once.Do(func() {
+		s.mutex.Lock()
+		s.ended = true
+		s.record.End = time.Now()
+		s.record.Duration = s.record.End.Sub(s.record.Start).Microseconds()
+		if s.record.Status == "" {
+			s.record.Status = OK.Status()
+		}
+		for _, arg := range args {
+			applySpanStatus(&s.record, arg)
+		}
+		s.mutex.Unlock()
+
+		s.meta.leaveSpan()
+		if s.finish != nil {
+			s.finish(s.record)
+		}
+	})
+}
+
+// SetAttr sets one span attribute.
+func (s *metaSpan) SetAttr(key string, value Any) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.ended || key == "" {
+		return
+	}
+	s.record.Attrs[key] = value
+}
+
+// AddEvent records a named event at the current time.
+func (s *metaSpan) AddEvent(name string, attrs ...Map) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.ended || name == "" {
+		return
+	}
+	event := SpanEvent{Name: name, Time: time.Now()}
+	if merged := mergeMetaAttrs(attrs...); len(merged) > 0 {
+		event.Attrs = merged
+	}
+	s.record.Events = append(s.record.Events, event)
+}
+
+// RecordError marks the span failed with a Res or error, and records an exception event.
+func (s *metaSpan) RecordError(err Any) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.ended {
+		return
+	}
+	record := SpanRecord{}
+	applySpanStatus(&record, err)
+	if record.Error == "" {
+		return
+	}
+	s.record.Code = record.Code
+	s.record.Status = record.Status
+	s.record.Error = record.Error
+	s.record.Events = append(s.record.Events, SpanEvent{
+		Name:  "exception",
+		Time:  time.Now(),
+		Attrs: Map{"exception.message": record.Error},
+	})
+}
+
+// Link adds a link to the span identified by a W3C traceparent.
+func (s *metaSpan) Link(traceparent string, attrs ...Map) {
+	target := NewMeta()
+	if !target.ParseTraceParent(traceparent) {
+		return
+	}
+	link := SpanLink{TraceId: target.TraceId(), SpanId: target.ParentSpanId()}
+	if merged := mergeMetaAttrs(attrs...); len(merged) > 0 {
+		link.Attrs = merged
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.ended {
+		return
+	}
+	s.record.Links = append(s.record.Links, link)
+}
+
+// endSpan ends span with res only when it failed.
+func endSpan(span TraceSpan, res Res) {
+	if res != nil && res.Fail() {
+		span.End(res)
+	} else {
+		span.End()
+	}
+}
+
+func applySpanStatus(record *SpanRecord, arg Any) {
+	switch v := arg.(type) {
+	case Res:
+		if v == nil {
+			return
+		}
+		record.Code = v.Code()
+		record.Status = v.Status()
+		if v.Fail() {
+			record.Error = v.Error()
+		}
+	case error:
+		record.Code = -1
+		record.Status = Fail.Status()
+		record.Error = v.Error()
+	}
+}
+
+func (h *defaultTraceHook) Register(string, Any) {}
+
+// Config reads [infrago.trace] buffer and file.
+func (h *defaultTraceHook) Config(global Map) {
+	runtimeCfg, ok := global["infrago"].(Map)
+	if !ok {
+		return
+	}
+	cfg, ok := runtimeCfg["trace"].(Map)
+	if !ok {
+		return
+	}
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if size := settingInt(cfg["buffer"]); size > 0 && size != h.size {
+		h.size = size
+		h.spans, h.next, h.full = nil, 0, false
+	}
+	if file, ok := cfg["file"].(string); ok {
+		h.file = file
+	}
+}
+
+func (h *defaultTraceHook) Setup() {}
+func (h *defaultTraceHook) Open()  {}
+func (h *defaultTraceHook) Start() {}
+func (h *defaultTraceHook) Stop()  {}
+
+func (h *defaultTraceHook) Close() {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if h.writer != nil {
+		_ = h.writer.Close()
+		h.writer = nil
+	}
+}
+
+func (h *defaultTraceHook) Begin(meta *Meta, name string, attrs Map) TraceSpan {
+	if meta == nil {
+		return noopTraceSpan{}
+	}
+	return beginMetaSpan(meta, name, attrs, h.record)
+}
+
+// Trace records one instant span under the current span.
+func (h *defaultTraceHook) Trace(meta *Meta, name string, status string, attrs Map) error {
+	if meta == nil {
+		return errors.New("trace requires meta")
+	}
+	span := beginMetaSpan(meta, name, attrs, h.record)
+	if status != "" {
+		span.record.Status = status
+	}
+	span.once.Do(func() {
+		span.record.End = span.record.Start
+		meta.leaveSpan()
+		h.record(span.record)
+	})
+	return nil
+}
+
+func (h *defaultTraceHook) record(record SpanRecord) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if h.size <= 0 {
+		h.size = defaultTraceBuffer
+	}
+	if h.spans == nil {
+		h.spans = make([]SpanRecord, h.size)
+	}
+	h.spans[h.next] = record
+	h.next = (h.next + 1) % h.size
+	if h.next == 0 {
+		h.full = true
+	}
+
+	if h.file == "" {
+		return
+	}
+	if h.writer == nil {
+		writer, err := os.OpenFile(h.file, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			hook.Log(nil, slog.LevelWarn, "trace file open failed", "file", h.file, "error", err)
+			h.file = ""
+			return
+		}
+		h.writer = writer
+	}
+	if line, err := json.Marshal(record); err == nil {
+		_, _ = h.writer.Write(append(line, '\n'))
+	}
+}
+
+// Spans returns buffered spans, oldest first.
+func (h *defaultTraceHook) Spans() []SpanRecord {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if !h.full {
+		out := make([]SpanRecord, h.next)
+		copy(out, h.spans[:h.next])
+		return out
+	}
+	out := make([]SpanRecord, 0, len(h.spans))
+	out = append(out, h.spans[h.next:]...)
+	return append(out, h.spans[:h.next]...)
+}
+
+// SetAttr sets one attribute on the current span.
+func (s *Span) SetAttr(key string, value Any) {
+	if annotator, ok := s.annotator(); ok {
+		annotator.SetAttr(key, value)
+	}
+}
+
+// AddEvent records a named event, like "cache miss", on the current span.
+func (s *Span) AddEvent(name string, attrs ...Map) {
+	if annotator, ok := s.annotator(); ok {
+		annotator.AddEvent(name, attrs...)
+	}
+}
+
+// RecordError marks the current span failed with a Res or error.
+func (s *Span) RecordError(err Any) {
+	if annotator, ok := s.annotator(); ok {
+		annotator.RecordError(err)
+	}
+}
+
+// Link links the current span to another span by its W3C traceparent.
+func (s *Span) Link(traceparent string, attrs ...Map) {
+	if annotator, ok := s.annotator(); ok {
+		annotator.Link(traceparent, attrs...)
+	}
+}
+
+func (s *Span) annotator() (SpanAnnotator, bool) {
+	if s == nil || s.span == nil {
+		return nil, false
+	}
+	annotator, ok := s.span.(SpanAnnotator)
+	return annotator, ok
+}
+
+// TraceSpans returns spans recorded by the default trace hook, oldest first.
+func TraceSpans() []SpanRecord {
+	return defaultTrace.Spans()
+}
@@ -0,0 +1,141 @@
+package infra
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/infrago/base"
+)
+
+func TestDefaultTraceHookGeneratesIDs(t *testing.T) {
+	h := &defaultTraceHook{size: 4}
+	meta := NewMeta()
+
+	outer := h.Begin(meta, "method:outer", TraceAttrs("infrago", TraceKindMethod, "outer"))
+	outerId := meta.SpanId()
+	if len(meta.TraceId()) != 32 || len(outerId) != 16 || meta.ParentSpanId() != "" {
+		t.Fatalf("unexpected outer ids trace=%q span=%q parent=%q", meta.TraceId(), outerId, meta.ParentSpanId())
+	}
+	if meta.TraceParent() == "00-00000000000000000000000000000000-0000000000000000-01" {
+		t.Fatalf("expected generated traceparent")
+	}
+
+	inner := h.Begin(meta, "method:inner", TraceAttrs("infrago", TraceKindMethod, "inner"))
+	if meta.ParentSpanId() != outerId || meta.TraceEntry() != "inner" {
+		t.Fatalf("expected inner span to be child of outer")
+	}
+	inner.End(Invalid)
+	if meta.SpanId() != outerId || meta.TraceEntry() != "outer" {
+		t.Fatalf("expected outer span to be restored")
+	}
+	outer.End()
+
+	spans := h.Spans()
+	if len(spans) != 2 || spans[0].Name != "method:inner" || spans[1].Name != "method:outer" {
+		t.Fatalf("unexpected spans: %#v", spans)
+	}
+	if spans[0].ParentSpanId != outerId || spans[0].Status != Invalid.Status() || spans[0].Error == "" {
+		t.Fatalf("unexpected inner record: %#v", spans[0])
+	}
+	if spans[1].Status != OK.Status() || spans[1].Kind != TraceKindMethod {
+		t.Fatalf("unexpected outer record: %#v", spans[1])
+	}
+}
+
+func TestDefaultTraceHookRingBufferAndFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "trace.jsonl")
+	h := &defaultTraceHook{size: 2, file: file}
+	defer h.Close()
+
+	meta := NewMeta()
+	for _, name := range []string{"a", "b", "c"} {
+		h.Begin(meta, name, nil).End()
+	}
+
+	spans := h.Spans()
+	if len(spans) != 2 || spans[0].Name != "b" || spans[1].Name != "c" {
+		t.Fatalf("expected oldest span to be evicted, got %#v", spans)
+	}
+
+	h.Close()
+	reader, err := os.Open(file)
+	if err != nil {
+		t.Fatalf("open trace file: %v", err)
+	}
+	defer reader.Close()
+	lines := 0
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		record := SpanRecord{}
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			t.Fatalf("decode line: %v", err)
+		}
+		lines++
+	}
+	if lines != 3 {
+		t.Fatalf("expected 3 json lines, got %d", lines)
+	}
+}
+
+func TestContextSpanAnnotatesInvocation(t *testing.T) {
+	traces := &defaultTraceHook{size: 8}
+	originalCore := core
+	core = &coreModule{
+		entries: map[string]coreEntry{
+			"demo.cache": {
+				kind: coreKindMethod,
+				Action: func(ctx *Context) Map {
+					span := ctx.Span()
+					span.SetAttr("cache.key", "user:1")
+					span.AddEvent("cache miss", Map{"layer": "redis"})
+					span.Link("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+					span.RecordError(errors.New("backend down"))
+					return Map{}
+				},
+			},
+		},
+	}
+	originalHook := hook
+	hook = &infragoHook{}
+	hook.AttachTrace(traces)
+	defer func() {
+		core = originalCore
+		hook = originalHook
+	}()
+
+	meta := NewMeta()
+	meta.Execute("demo.cache")
+	if meta.Span().span != nil {
+		t.Fatalf("expected no current span after invocation")
+	}
+
+	spans := traces.Spans()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(spans))
+	}
+	span := spans[0]
+	if span.Attrs["cache.key"] != "user:1" || span.Error != "backend down" || span.Status != Fail.Status() {
+		t.Fatalf("unexpected span record: %#v", span)
+	}
+	if len(span.Events) != 2 || span.Events[0].Name != "cache miss" || span.Events[1].Name != "exception" {
+		t.Fatalf("unexpected events: %#v", span.Events)
+	}
+	if len(span.Links) != 1 || span.Links[0].SpanId != "00f067aa0ba902b7" {
+		t.Fatalf("unexpected links: %#v", span.Links)
+	}
+}
+
+func TestSpanIgnoresHooksWithoutAnnotations(t *testing.T) {
+	span := &Span{span: noopTraceSpan{}}
+	span.SetAttr("k", "v")
+	span.AddEvent("e")
+	span.RecordError(errors.New("x"))
+	span.Link("invalid")
+
+	var empty *Span
+	empty.SetAttr("k", "v")
+}
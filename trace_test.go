@@ -0,0 +1,76 @@
+package infra
+
+import "testing"
+
+func TestParseTraceParentValidation(t *testing.T) {
+	cases := []struct {
+		value   string
+		ok      bool
+		sampled bool
+	}{
+		{value: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", ok: true, sampled: true},
+		{value: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00", ok: true, sampled: false},
+		{value: "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", ok: false},
+		{value: "00-00000000000000000000000000000000-00f067aa0ba902b7-01", ok: false},
+		{value: "00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01", ok: false},
+		{value: "00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01", ok: false},
+		{value: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra", ok: false},
+		{value: "cc-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-03-extra", ok: true, sampled: true},
+	}
+	for _, c := range cases {
+		meta := NewMeta()
+		if ok := meta.ParseTraceParent(c.value); ok != c.ok {
+			t.Fatalf("%s: expected ok=%v, got %v", c.value, c.ok, ok)
+		}
+		if c.ok && meta.Sampled() != c.sampled {
+			t.Fatalf("%s: expected sampled=%v", c.value, c.sampled)
+		}
+	}
+
+	meta := NewMeta()
+	meta.ParseTraceParent("cc-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-03-extra")
+	if meta.TraceFlags() != 0x01 {
+		t.Fatalf("expected unknown flags of future versions to be dropped, got %x", meta.TraceFlags())
+	}
+}
+
+func TestTraceStateAndFlagsInMetadata(t *testing.T) {
+	meta := NewMeta()
+	meta.ParseTraceParent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+	meta.TraceState(" rojo=00f067aa0ba902b7 ,, congo@vendor=t61rcWkgMzE ")
+	if meta.TraceState() != "rojo=00f067aa0ba902b7,congo@vendor=t61rcWkgMzE" {
+		t.Fatalf("unexpected tracestate %q", meta.TraceState())
+	}
+
+	remote := NewMeta()
+	remote.Metadata(meta.Metadata())
+	if remote.Sampled() || remote.TraceState() != meta.TraceState() {
+		t.Fatalf("expected flags and tracestate to round trip, got %q %q", remote.Metadata().TraceFlags, remote.TraceState())
+	}
+
+	if meta.TraceState("a=1,a=2") != "" {
+		t.Fatalf("expected duplicated keys to invalidate tracestate")
+	}
+	if meta.TraceState("Upper=1") != "" {
+		t.Fatalf("expected invalid key to invalidate tracestate")
+	}
+}
+
+func TestUnsampledBeginKeepsPropagating(t *testing.T) {
+	meta := NewMeta()
+	meta.ParseTraceParent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+	meta.SpanId("00f067aa0ba902b7")
+
+	span := meta.Begin("method:demo")
+	if meta.SpanId() == "00f067aa0ba902b7" || meta.ParentSpanId() != "00f067aa0ba902b7" {
+		t.Fatalf("expected child span id, got span=%s parent=%s", meta.SpanId(), meta.ParentSpanId())
+	}
+	if parent := meta.TraceParent(); parent[len(parent)-2:] != "00" {
+		t.Fatalf("expected unsampled traceparent, got %s", parent)
+	}
+	span.End()
+	span.End()
+	if meta.SpanId() != "00f067aa0ba902b7" {
+		t.Fatalf("expected span id to be restored, got %s", meta.SpanId())
+	}
+}
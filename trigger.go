@@ -10,6 +10,9 @@ import (
 const (
 	START = "start"
 	STOP  = "stop"
+
+	// CONFIG_CHANGED fires after a config reload, with the changed keys in "keys".
+	CONFIG_CHANGED = "config.changed"
 )
 
 var (
@@ -83,26 +86,43 @@ func (m *triggerModule) nextMethodName(name string) string {
 	return "_." + name + "." + strconv.FormatUint(m.seq, 10)
 }
 
+// Toggle runs every action of trigger name asynchronously, each under the trigger span.
 func (m *triggerModule) Toggle(name string, values ...Map) {
-	value := Map{}
-	if len(values) > 0 && values[0] != nil {
-		value = values[0]
-	}
-	if ms, ok := m.methods[name]; ok {
-		for _, methodName := range ms {
-			go core.Invoke(nil, methodName, value)
-		}
-	}
+	m.toggle(name, false, values...)
 }
 
+// SyncToggle runs every action of trigger name and waits for them in order.
 func (m *triggerModule) SyncToggle(name string, values ...Map) {
+	m.toggle(name, true, values...)
+}
+
+func (m *triggerModule) toggle(name string, wait bool, values ...Map) {
 	value := Map{}
 	if len(values) > 0 && values[0] != nil {
 		value = values[0]
 	}
-	if ms, ok := m.methods[name]; ok {
-		for _, methodName := range ms {
-			core.Invoke(nil, methodName, value)
+	m.mutex.Lock()
+	ms := m.methods[name]
+	m.mutex.Unlock()
+	if len(ms) == 0 {
+		return
+	}
+
+	meta := NewMeta()
+	span := meta.Begin("trigger:"+name, TraceAttrs("infrago", TraceKindTrigger, name, Map{
+		"module":    "trigger",
+		"operation": "toggle",
+		"step":      "producer",
+	}))
+	defer span.End()
+
+	// fork before launching, actions outlive the trigger span.
+	for _, methodName := range ms {
+		actionMeta := meta.Fork()
+		if wait {
+			core.Invoke(actionMeta, methodName, value)
+		} else {
+			go core.Invoke(actionMeta, methodName, value)
 		}
 	}
 }